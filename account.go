@@ -27,8 +27,10 @@
 package betfair
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
+	"time"
 )
 
 // Response for Account details.
@@ -72,29 +74,208 @@ type DeveloperAppVersion struct {
 	Active               bool
 }
 
+// IncludeItemVal Enum for the items of the account statement
+type IncludeItemVal baseEnumVal
+
+// IncludeItem
+const (
+	IncludeItemAll                 IncludeItemVal = "ALL"
+	IncludeItemDepositsWithdrawals                = "DEPOSITS_WITHDRAWALS"
+	IncludeItemExchange                           = "EXCHANGE"
+	IncludeItemPokerRoom                          = "POKER_ROOM"
+)
+
+// WalletVal Enum for the wallets
+type WalletVal baseEnumVal
+
+// Wallet
+const (
+	WalletUK         WalletVal = "UK"
+	WalletAustralian           = "AUSTRALIAN"
+)
+
+// ItemClassVal Enum for the classes of statement items
+type ItemClassVal baseEnumVal
+
+// ItemClass
+const (
+	ItemClassUnknown ItemClassVal = "UNKNOWN"
+)
+
+// AccountStatementParams sets up the parameters for the account statement.
+// All fields are optional; RecordCount is the page size, up to 100.
+type AccountStatementParams struct {
+	FromRecord    int            `json:"fromRecord,omitempty"`
+	RecordCount   int            `json:"recordCount,omitempty"`
+	ItemDateRange *TimeRange     `json:"itemDateRange,omitempty"`
+	IncludeItem   IncludeItemVal `json:"includeItem,omitempty"`
+	Wallet        WalletVal      `json:"wallet,omitempty"`
+}
+
+type accountStatementParams struct {
+	Locale string `json:"locale,omitempty"`
+	*AccountStatementParams
+}
+
+// Legacy data of a statement item, describing the bet it comes from.
+type StatementLegacyData struct {
+	AvgPrice        float64
+	BetSize         float64
+	BetType         string
+	BetCategoryType string
+	CommissionRate  string
+	EventId         int64
+	EventTypeId     int64
+	FullMarketName  string
+	GrossBetAmount  float64
+	MarketName      string
+	MarketType      string
+	PlacedDate      time.Time
+	SelectionId     int64
+	SelectionName   string
+	StartDate       time.Time
+	TransactionType string
+	TransactionId   int64
+	WinLose         string
+}
+
+// An item of the account statement.
+type StatementItem struct {
+	RefId         string
+	ItemDate      time.Time
+	Amount        float64
+	Balance       float64
+	ItemClass     ItemClassVal
+	ItemClassData map[string]string
+	LegacyData    StatementLegacyData
+}
+
+// Response for the account statement.
+type AccountStatementReport struct {
+	AccountStatement []StatementItem
+	MoreAvailable    bool
+}
+
+// AccountStatementIterator walks through every item of the account
+// statement, requesting the pages as needed.
+type AccountStatementIterator struct {
+	pager
+	ctx     context.Context
+	s       *Session
+	params  AccountStatementParams
+	items   []StatementItem
+	current StatementItem
+}
+
+// Next advances to the next item, returning false when there are no more
+// items or a request failed.
+func (it *AccountStatementIterator) Next() bool {
+	if !it.next(it.fetch) {
+		return false
+	}
+	it.current, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+func (it *AccountStatementIterator) fetch(from int) (int, bool, error) {
+	it.params.FromRecord = from
+	report, err := it.s.GetAccountStatementContext(it.ctx, &it.params)
+	it.items = report.AccountStatement
+	return len(report.AccountStatement), report.MoreAvailable, err
+}
+
+// Item returns the item the iterator is positioned on.
+func (it *AccountStatementIterator) Item() StatementItem {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *AccountStatementIterator) Err() error {
+	return it.err
+}
+
 // Get Account details.
 func (s *Session) GetAccountDetails() (AccountDetailsResponse, error) {
+	return s.GetAccountDetailsContext(context.Background())
+}
+
+// GetAccountDetailsContext is like GetAccountDetails, but the request is bound to ctx.
+func (s *Session) GetAccountDetailsContext(ctx context.Context) (AccountDetailsResponse, error) {
 	var response AccountDetailsResponse
-	err := doAccountRequest(s, "getAccountDetails", &response)
+	err := doAccountRequest(ctx, s, "getAccountDetails", nil, &response)
 	return response, err
 }
 
 // Get available to bet amount.
 func (s *Session) GetAccountFunds() (AccountFundsResponse, error) {
+	return s.GetAccountFundsContext(context.Background())
+}
+
+// GetAccountFundsContext is like GetAccountFunds, but the request is bound to ctx.
+func (s *Session) GetAccountFundsContext(ctx context.Context) (AccountFundsResponse, error) {
 	var response AccountFundsResponse
-	err := doAccountRequest(s, "getAccountFunds", &response)
+	err := doAccountRequest(ctx, s, "getAccountFunds", nil, &response)
 	return response, err
 }
 
 // Get all application keys owned by the given developer/vendor.
 func (s *Session) GetDeveloperAppKeys() ([]DeveloperApp, error) {
+	return s.GetDeveloperAppKeysContext(context.Background())
+}
+
+// GetDeveloperAppKeysContext is like GetDeveloperAppKeys, but the request is bound to ctx.
+func (s *Session) GetDeveloperAppKeysContext(ctx context.Context) ([]DeveloperApp, error) {
 	var response []DeveloperApp
-	err := doAccountRequest(s, "getDeveloperAppKeys", &response)
+	err := doAccountRequest(ctx, s, "getDeveloperAppKeys", nil, &response)
 	return response, err
 }
 
-func doAccountRequest(s *Session, method string, v interface{}) error {
-	data, err := doRequest(s, "account", method, strings.NewReader(""))
+// Get the account statement, a page of up to 100 items.
+func (s *Session) GetAccountStatement(params *AccountStatementParams) (AccountStatementReport, error) {
+	return s.GetAccountStatementContext(context.Background(), params)
+}
+
+// GetAccountStatementContext is like GetAccountStatement, but the request is bound to ctx.
+func (s *Session) GetAccountStatementContext(ctx context.Context, params *AccountStatementParams) (AccountStatementReport, error) {
+	var report AccountStatementReport
+	if params == nil {
+		params = new(AccountStatementParams)
+	}
+	request := &accountStatementParams{
+		Locale:                 s.config.Locale,
+		AccountStatementParams: params,
+	}
+	err := doAccountRequest(ctx, s, "getAccountStatement", request, &report)
+	return report, err
+}
+
+// IterateAccountStatement Returns an iterator over all the items of the
+// account statement, following moreAvailable from params.FromRecord onwards.
+func (s *Session) IterateAccountStatement(params *AccountStatementParams) *AccountStatementIterator {
+	return s.IterateAccountStatementContext(context.Background(), params)
+}
+
+// IterateAccountStatementContext is like IterateAccountStatement, but every request is bound to ctx.
+func (s *Session) IterateAccountStatementContext(ctx context.Context, params *AccountStatementParams) *AccountStatementIterator {
+	it := &AccountStatementIterator{ctx: ctx, s: s}
+	if params != nil {
+		it.params = *params
+		it.from = params.FromRecord
+	}
+	return it
+}
+
+// Sends params, if any, as the JSON body of the request.
+func doAccountRequest(ctx context.Context, s *Session, method string, params interface{}, v interface{}) error {
+	body := strings.NewReader("")
+	if params != nil {
+		bytes, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		body = strings.NewReader(string(bytes))
+	}
+	data, err := doRequest(ctx, s, "account", method+"/", body)
 	if err != nil {
 		return err
 	}
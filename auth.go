@@ -24,8 +24,10 @@
 package betfair
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"net/url"
 	"strings"
 )
 
@@ -46,10 +48,45 @@ type keepAliveResult struct {
 // username and password, to authenticate your credentials and generate a
 // session token.
 func (s *Session) LoginNonInteractive() error {
+	return s.LoginNonInteractiveContext(context.Background())
+}
+
+// LoginNonInteractiveContext is like LoginNonInteractive, but the request is bound to ctx.
+func (s *Session) LoginNonInteractiveContext(ctx context.Context) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	return s.loginNonInteractive(ctx)
+}
+
+// The interactive login method for API-NG authenticates with username and
+// password only, no certificate is needed. It requires Config.AppKey, sent
+// as X-Application, and Config.Interactive to be able to login again
+// automatically.
+func (s *Session) LoginInteractive() error {
+	return s.LoginInteractiveContext(context.Background())
+}
 
+// LoginInteractiveContext is like LoginInteractive, but the request is bound to ctx.
+func (s *Session) LoginInteractiveContext(ctx context.Context) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	return s.loginInteractive(ctx)
+}
+
+// Logs in with the configured method, the caller holds loginMu.
+func (s *Session) login(ctx context.Context) error {
+	if s.config.Interactive {
+		return s.loginInteractive(ctx)
+	}
+	return s.loginNonInteractive(ctx)
+}
+
+func (s *Session) loginNonInteractive(ctx context.Context) error {
+
+	ctx = withoutRelogin(ctx)
 	body := strings.NewReader("username=" + s.config.Username + "&password=" + s.config.Password)
 
-	data, err := doRequest(s, "certLogin", "", body)
+	data, err := doRequest(ctx, s, EndpointCertLogin, "", body)
 	if err != nil {
 		return err
 	}
@@ -60,40 +97,123 @@ func (s *Session) LoginNonInteractive() error {
 		return err
 	}
 	if result.LoginStatus != "SUCCESS" {
-		return errors.New(result.LoginStatus)
+		return &APIError{Method: EndpointCertLogin, StatusCode: 200, ErrorCode: ErrorCodeVal(result.LoginStatus)}
+	}
+
+	return s.loggedIn(ctx, result.SessionToken)
+}
+
+func (s *Session) loginInteractive(ctx context.Context) error {
+
+	if s.config.AppKey == "" {
+		return errors.New("Config.AppKey is empty.")
 	}
+	ctx = withoutRelogin(ctx)
+	form := url.Values{}
+	form.Set("username", s.config.Username)
+	form.Set("password", s.config.Password)
 
-	s.token = result.SessionToken
-	// Get application keys. It seems we currently have one dev app only.
-	apps, err := s.GetDeveloperAppKeys()
+	data, err := doRequest(ctx, s, EndpointLogin, "", strings.NewReader(form.Encode()))
 	if err != nil {
 		return err
 	}
-	if len(apps) < 1 {
-		return errors.New("Cannot get app keys")
+
+	var result keepAliveResult
+	if err = json.Unmarshal(data, &result); err != nil {
+		return err
 	}
-	if len(apps[0].AppVersions) != 2 {
-		return errors.New("Invalid amount of app versions")
+	// LIMITED_ACCESS and LOGIN_RESTRICTED come with an error code too.
+	if result.Status != "SUCCESS" {
+		return &APIError{Method: EndpointLogin, StatusCode: 200, ErrorCode: ErrorCodeVal(result.Error), Message: result.Status}
 	}
-	if apps[0].AppVersions[0].DelayData {
-		s.appKeys[DELAY_DATA] = apps[0].AppVersions[0].ApplicationKey
-		s.appKeys[LIVE_DATA] = apps[0].AppVersions[1].ApplicationKey
+
+	return s.loggedIn(ctx, result.Token)
+}
+
+// Stores the session token and gets the application keys.
+func (s *Session) loggedIn(ctx context.Context, token string) error {
+
+	s.setToken(token)
+	if s.config.AppKey != "" || s.config.DelayedAppKey != "" {
+		s.setAppKeys(s.config.AppKey, s.config.DelayedAppKey)
 	} else {
-		s.appKeys[DELAY_DATA] = apps[0].AppVersions[1].ApplicationKey
-		s.appKeys[LIVE_DATA] = apps[0].AppVersions[0].ApplicationKey
+		apps, err := s.GetDeveloperAppKeysContext(ctx)
+		if err != nil {
+			return err
+		}
+		live, delayed, err := selectAppKeys(apps, s.config.AppName, s.config.AppVersion)
+		if err != nil {
+			return err
+		}
+		s.setAppKeys(live, delayed)
 	}
+	s.startKeepAlive()
 
-	return nil
+	return s.saveState()
+}
+
+// Returns the live and delayed keys of the application named name, or of
+// the first one with active keys if name is empty. Inactive versions are
+// skipped, and both
+// keys come from a single version: the given one, else the first one with
+// both keys, else the first one.
+func selectAppKeys(apps []DeveloperApp, name, version string) (string, string, error) {
+	for _, app := range apps {
+		if name != "" && app.AppName != name {
+			continue
+		}
+		// The delayed key of version "1.0" comes as version "1.0-DELAY".
+		var versions []string
+		keys := make(map[string]*[2]string)
+		for _, v := range app.AppVersions {
+			base := strings.TrimSuffix(v.Version, "-DELAY")
+			if !v.Active || (version != "" && base != version) {
+				continue
+			}
+			k, exists := keys[base]
+			if !exists {
+				k = new([2]string)
+				keys[base] = k
+				versions = append(versions, base)
+			}
+			data := LIVE_DATA
+			if v.DelayData {
+				data = DELAY_DATA
+			}
+			if k[data] == "" {
+				k[data] = v.ApplicationKey
+			}
+		}
+		for _, base := range versions {
+			if k := keys[base]; k[LIVE_DATA] != "" && k[DELAY_DATA] != "" {
+				return k[LIVE_DATA], k[DELAY_DATA], nil
+			}
+		}
+		if len(versions) > 0 {
+			k := keys[versions[0]]
+			return k[LIVE_DATA], k[DELAY_DATA], nil
+		}
+		// Without a name, look for active keys in the next apps.
+		if name != "" {
+			break
+		}
+	}
+	return "", "", errors.New("Cannot get app keys")
 }
 
 // You can use Keep Alive to reset the session timeout.
 // The session time is currently 20 minutes.  Therefore, you should request Keep Alive
 // within this time to prevent session expiry.
 func (s *Session) KeepAlive() error {
+	return s.KeepAliveContext(context.Background())
+}
+
+// KeepAliveContext is like KeepAlive, but the request is bound to ctx.
+func (s *Session) KeepAliveContext(ctx context.Context) error {
 
 	var result keepAliveResult
 
-	data, err := doRequest(s, "auth", "keepAlive", strings.NewReader(""))
+	data, err := doRequest(ctx, s, "auth", "keepAlive", strings.NewReader(""))
 
 	if err != nil {
 		return err
@@ -102,18 +222,25 @@ func (s *Session) KeepAlive() error {
 		return err
 	}
 	if result.Status != "SUCCESS" {
-		return errors.New(result.Error)
+		return &APIError{Method: "keepAlive", StatusCode: 200, ErrorCode: ErrorCodeVal(result.Error)}
 	}
 
 	return nil
 }
 
-// Logout from Betfair.
+// Logout from Betfair. It also stops the keep-alive goroutine.
 func (s *Session) Logout() error {
+	return s.LogoutContext(context.Background())
+}
+
+// LogoutContext is like Logout, but the request is bound to ctx.
+func (s *Session) LogoutContext(ctx context.Context) error {
+
+	s.Close()
 
 	var result keepAliveResult
 
-	data, err := doRequest(s, "auth", "logout", strings.NewReader(""))
+	data, err := doRequest(ctx, s, "auth", "logout", strings.NewReader(""))
 
 	if err != nil {
 		return err
@@ -122,7 +249,7 @@ func (s *Session) Logout() error {
 		return err
 	}
 	if result.Status != "SUCCESS" {
-		return errors.New(result.Error)
+		return &APIError{Method: "logout", StatusCode: 200, ErrorCode: ErrorCodeVal(result.Error)}
 	}
 
 	return nil
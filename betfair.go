@@ -29,14 +29,20 @@
 package betfair
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -45,30 +51,16 @@ const (
 	DELAY_DATA = 1
 )
 
-var ukEndpoints = map[string][]string{
-	"certLogin": {"https://identitysso-api.betfair.com/api/certlogin", "POST"},
-	"auth":      {"https://identitysso.betfair.com/api/", "POST"},
-	"betting":   {"https://api.betfair.com/exchange/betting/rest/v1.0/", "POST"},
-	"account":   {"https://api.betfair.com/exchange/account/rest/v1.0/", "GET"},
-}
-
-var auEndpoints = map[string][]string{
-	"certLogin": {"https://identitysso-api.betfair.com/api/certlogin", "POST"},
-	"auth":      {"https://identitysso.betfair.com/api/", "POST"},
-	"betting":   {"https://api-au.betfair.com/exchange/betting/rest/v1.0/", "POST"},
-	"account":   {"https://api-au.betfair.com/exchange/account/rest/v1.0/", "GET"},
-}
-
-var endpointMap = map[string]map[string][]string{
-	"UK": ukEndpoints,
-	"AU": auEndpoints,
-}
-
+// RequestSpecification is the URL and HTTP method of a request.
 type RequestSpecification struct {
 	Url  string
 	Type string
 }
 
+// Config of a session. Only Username, Password, CertFile and KeyFile are
+// mandatory, the HTTP settings fall back to sensible defaults when zero.
+// The interactive login needs AppKey instead of CertFile and KeyFile, which
+// may also be replaced by an in-memory certificate.
 type Config struct {
 	Username string
 	Password string
@@ -76,14 +68,101 @@ type Config struct {
 	KeyFile  string
 	Exchange string
 	Locale   string
+
+	// Interactive selects the interactive login, which needs AppKey but no
+	// certificate.
+	Interactive bool
+	// AppKey is the application key sent with the interactive login. When
+	// set, it is also used for every request and the application keys are
+	// not fetched with GetDeveloperAppKeys at login.
+	AppKey string
+	// DelayedAppKey, when set, is used instead of AppKey for delayed data.
+	// If only one of the two keys is set, it is used for both.
+	DelayedAppKey string
+	// AppName and AppVersion select the application, and its version, whose
+	// keys are fetched at login when no key is given. They default to the
+	// first application with active keys and its first active version with
+	// both a live and a delayed key.
+	AppName    string
+	AppVersion string
+
+	// The client certificate may be given in memory instead of CertFile and
+	// KeyFile: as PEM blocks, as a PKCS#12 bundle or through a provider.
+	CertPEM             []byte
+	KeyPEM              []byte
+	PKCS12              []byte
+	PKCS12Password      string
+	CertificateProvider CertificateProvider `json:"-"`
+
+	// Endpoints, when set, replaces the endpoints of Exchange, i.e. to
+	// point the session at a local test server (see NewEndpoints).
+	Endpoints Endpoints `json:"-"`
+
+	// HTTPClient, when set, is used as is for every request. It is up to
+	// the caller to configure the client certificate on it.
+	HTTPClient *http.Client `json:"-"`
+	// Transport, when set, replaces the default transport of the client.
+	// As for HTTPClient, the client certificate has to be configured on it.
+	Transport http.RoundTripper `json:"-"`
+	// Timeout limits the whole request, including reading the body.
+	Timeout time.Duration
+	// DialTimeout defaults to 3 seconds.
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	// EnableHTTP2 attempts HTTP/2, which is off by default.
+	EnableHTTP2 bool
+	// ProxyURL is the proxy to use for every request, i.e.
+	// "http://proxy.example.com:3128".
+	ProxyURL string
+
+	// RetryPolicy, when set, retries failed read requests.
+	RetryPolicy *RetryPolicy
+	// RateLimiter, when set, throttles every request of the session.
+	RateLimiter *RateLimiter `json:"-"`
+
+	// KeepAliveInterval, when set, keeps the session alive from a
+	// goroutine started at login and stopped by Close or Logout. The
+	// session expires after 20 minutes.
+	KeepAliveInterval time.Duration
+	// OnKeepAliveError, when set, is called with the errors of the
+	// keep-alive goroutine.
+	OnKeepAliveError func(err error) `json:"-"`
+	// AutoRelogin logs in again and retries once the requests failing
+	// because the session expired.
+	AutoRelogin bool
+	// TokenStore, when set, saves the session at every login so that it
+	// can be resumed after a restart, see Session.Resume.
+	TokenStore TokenStore `json:"-"`
+
+	// RootCAs verifies the server certificates instead of the system roots.
+	RootCAs *x509.CertPool `json:"-"`
+	// PinnedSPKI restricts the accepted server certificates to the ones
+	// whose chain contains one of these base64 encoded SHA-256 hashes of
+	// the Subject Public Key Info.
+	PinnedSPKI []string
+	// InsecureSkipVerifyForTestingOnly disables the verification of server
+	// certificates. Never use it against betfair, it exposes your password.
+	InsecureSkipVerifyForTestingOnly bool
 }
 
+// Session is safe for concurrent use by multiple goroutines: requests may
+// run while logging in, keeping alive or switching live data on and off.
+// A request uses the token and application key current when it starts.
 type Session struct {
-	config     *Config
-	httpClient *http.Client
-	token      string
-	appKeys    [2]string
-	Live       bool
+	config        *Config
+	endpoints     Endpoints
+	httpClient    *http.Client
+	mu            sync.RWMutex
+	token         string
+	issuedAt      time.Time
+	appKeys       [2]string
+	live          bool
+	loginMu       sync.Mutex
+	stopKeepAlive chan struct{}
 }
 
 // Create a new session. Please note that you have to login to retrieve a
@@ -99,100 +178,310 @@ func NewSession(c *Config) (*Session, error) {
 	if c.Password == "" {
 		return s, errors.New("Config.Password is empty.")
 	}
-	if _, err := os.Stat(c.CertFile); os.IsNotExist(err) {
-		return s, errors.New("Config.CertFile does not exist.")
-	}
-	if _, err := os.Stat(c.KeyFile); os.IsNotExist(err) {
-		return s, errors.New("Config.KeyFile does not exist.")
-	}
 	c.Exchange = strings.ToUpper(c.Exchange)
-	if _, exists := endpointMap[c.Exchange]; exists == false {
+	if c.Exchange == "" {
 		c.Exchange = "UK"
 	}
+	s.endpoints = c.Endpoints.clone()
+	if c.Endpoints == nil {
+		endpoints, err := DefaultEndpoints.Lookup(c.Exchange)
+		if err != nil {
+			return s, errors.New("Config.Exchange is unknown: " + c.Exchange)
+		}
+		s.endpoints = endpoints
+	}
 	if c.Locale == "" {
 		c.Locale = "en"
 	}
+	if c.DialTimeout == 0 {
+		c.DialTimeout = time.Duration(time.Second * 3)
+	}
 	s.config = c
 
 	// HTTP client
-	cert, err := tls.LoadX509KeyPair(s.config.CertFile, s.config.KeyFile)
+	if c.HTTPClient != nil {
+		s.httpClient = c.HTTPClient
+		return s, nil
+	}
+	s.httpClient = &http.Client{Timeout: c.Timeout}
+	if c.Transport != nil {
+		s.httpClient.Transport = c.Transport
+		return s, nil
+	}
+	var certs []tls.Certificate
+	if !c.Interactive {
+		var err error
+		if certs, err = loadCertificates(c); err != nil {
+			return s, err
+		}
+	}
+	ssl, err := newTLSConfig(c, certs)
 	if err != nil {
 		return s, err
 	}
+	transport, err := newTransport(c, ssl)
+	if err != nil {
+		return s, err
+	}
+	s.httpClient.Transport = transport
+
+	return s, nil
+}
+
+// Builds the TLS configuration of the default transport. Server certificates
+// are always verified, unless explicitly disabled for testing.
+func newTLSConfig(c *Config, certs []tls.Certificate) (*tls.Config, error) {
 	ssl := &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		InsecureSkipVerify: true,
+		Certificates:       certs,
+		RootCAs:            c.RootCAs,
+		InsecureSkipVerify: c.InsecureSkipVerifyForTestingOnly,
 	}
 	ssl.Rand = rand.Reader
-	s.httpClient = &http.Client{
-		Transport: &http.Transport{
-			Dial: func(network, addr string) (net.Conn, error) {
-				return net.DialTimeout(network, addr, time.Duration(time.Second*3))
-			},
-			TLSClientConfig: ssl,
-		},
+	if provider := c.CertificateProvider; provider != nil && !c.Interactive {
+		ssl.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return provider.ClientCertificate()
+		}
 	}
+	if len(c.PinnedSPKI) > 0 {
+		pins := make(map[string]bool)
+		for _, pin := range c.PinnedSPKI {
+			hash, err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(hash) != sha256.Size {
+				return nil, errors.New("Config.PinnedSPKI is invalid: " + pin)
+			}
+			pins[string(hash)] = true
+		}
+		ssl.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifyPins(pins, rawCerts, verifiedChains)
+		}
+	}
+	return ssl, nil
+}
 
-	return s, nil
+// Checks that a pinned public key is part of a verified chain or, when
+// verification is disabled, of the certificates presented by the server.
+func verifyPins(pins map[string]bool, rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	chains := verifiedChains
+	if len(chains) == 0 {
+		var chain []*x509.Certificate
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			chain = append(chain, cert)
+		}
+		chains = [][]*x509.Certificate{chain}
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if pins[string(hash[:])] {
+				return nil
+			}
+		}
+	}
+	return errors.New("No pinned public key in server certificates")
+}
+
+// Builds the default transport from the HTTP settings of the configuration.
+func newTransport(c *Config, ssl *tls.Config) (*http.Transport, error) {
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: c.DialTimeout,
+		}).DialContext,
+		TLSClientConfig:       ssl,
+		TLSHandshakeTimeout:   c.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: c.ResponseHeaderTimeout,
+		IdleConnTimeout:       c.IdleConnTimeout,
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		ForceAttemptHTTP2:     c.EnableHTTP2,
+	}
+	if c.ProxyURL != "" {
+		proxy, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, errors.New("Config.ProxyURL is invalid: " + err.Error())
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return transport, nil
+}
+
+func (s *Session) getToken() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token
+}
+
+func (s *Session) setToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	s.issuedAt = time.Now()
+}
+
+// Stores the application keys, one is used for both if the other is empty.
+func (s *Session) setAppKeys(live, delayed string) {
+	if live == "" {
+		live = delayed
+	}
+	if delayed == "" {
+		delayed = live
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appKeys[LIVE_DATA] = live
+	s.appKeys[DELAY_DATA] = delayed
+}
+
+type liveDataKey struct{}
+
+// WithLiveData selects live data for the requests bound to the returned
+// context, whatever SetLive selected.
+func WithLiveData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, liveDataKey{}, true)
+}
+
+// WithDelayedData selects delayed data for the requests bound to the
+// returned context, whatever SetLive selected.
+func WithDelayedData(ctx context.Context) context.Context {
+	return context.WithValue(ctx, liveDataKey{}, false)
+}
+
+// Returns the application key for live or delayed data, as selected by ctx
+// or else by SetLive.
+func (s *Session) getAppKey(ctx context.Context) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	live, selected := ctx.Value(liveDataKey{}).(bool)
+	if !selected {
+		live = s.live
+	}
+	if live {
+		return s.appKeys[LIVE_DATA]
+	}
+	return s.appKeys[DELAY_DATA]
+}
+
+// SetLive selects live data, which requires an activated live application
+// key, or delayed data for the following requests. It affects every caller
+// of the session, see WithLiveData to select the data of a single request.
+func (s *Session) SetLive(live bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.live = live
+}
+
+// IsLive tells whether requests use live data.
+func (s *Session) IsLive() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.live
 }
 
 // Builds URLs for API methods.
 func (s *Session) getRequestSpec(key, method string) (RequestSpecification, error) {
-	if _, exists := endpointMap[s.config.Exchange][key]; exists == false {
+	endpoint, exists := s.endpoints[key]
+	if exists == false {
 		return RequestSpecification{}, errors.New("Invalid endpoint key: " + key)
 	}
-	url := endpointMap[s.config.Exchange][key][0] + method
-	requestType := endpointMap[s.config.Exchange][key][1]
-	if requestType == "GET" {
-		url += "/"
-	}
-	return RequestSpecification{url, requestType}, nil
+	// The REST methods carry their trailing slash, whatever the HTTP method.
+	return RequestSpecification{endpoint.Url + method, endpoint.Type}, nil
 }
 
 // Makes requests to Betfair API via http client.
-func doRequest(s *Session, key, method string, body *strings.Reader) ([]byte, error) {
+// The request is bound to ctx, which covers dial, TLS handshake and reading
+// of the response body. Failed requests are retried according to
+// Config.RetryPolicy, and once more after logging in again when the session
+// expired and Config.AutoRelogin is set.
+func doRequest(ctx context.Context, s *Session, key, method string, body *strings.Reader) ([]byte, error) {
+	token := s.getToken()
+	data, err := doRetriedRequest(ctx, s, key, method, body)
+	if err == nil || !s.shouldRelogin(ctx, key, err) {
+		return data, err
+	}
+	if err := s.relogin(ctx, token); err != nil {
+		return nil, err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return doRetriedRequest(ctx, s, key, method, body)
+}
+
+func doRetriedRequest(ctx context.Context, s *Session, key, method string, body *strings.Reader) ([]byte, error) {
+	policy := s.config.RetryPolicy
+	if !policy.allows(ctx, method) {
+		return doSingleRequest(ctx, s, key, method, body)
+	}
+	for attempt := 1; ; attempt++ {
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		data, err := doSingleRequest(ctx, s, key, method, body)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return data, err
+		}
+		if err := policy.wait(ctx, attempt-1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func doSingleRequest(ctx context.Context, s *Session, key, method string, body *strings.Reader) ([]byte, error) {
+
+	if s.config.RateLimiter != nil {
+		if err := s.config.RateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
 
 	reqSpec, err := s.getRequestSpec(key, method)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest(reqSpec.Type, reqSpec.Url, body)
+	req, err := http.NewRequestWithContext(ctx, reqSpec.Type, reqSpec.Url, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Connection", "keep-alive")
-	if key == "certLogin" {
+	if key == EndpointCertLogin {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		// In non-interactive login, X-Application is not validated
 		req.Header.Set("X-Application", "Gofair")
+	} else if key == EndpointLogin {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Application", s.config.AppKey)
 	} else {
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-Authentication", s.token)
-		if key == "account" && method == "getDeveloperAppKeys" {
+		req.Header.Set("X-Authentication", s.getToken())
+		if key == "account" && method == "getDeveloperAppKeys/" {
 			req.Header.Del("X-Application")
 		} else {
-			if s.Live {
-				req.Header.Set("X-Application", s.appKeys[LIVE_DATA])
-			} else {
-				req.Header.Set("X-Application", s.appKeys[DELAY_DATA])
-			}
+			req.Header.Set("X-Application", s.getAppKey(ctx))
 		}
 	}
 	res, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	if res.StatusCode != 200 {
-		return nil, errors.New(res.Status)
-	}
 	defer res.Body.Close()
 	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		return nil, err
 	}
+	if res.StatusCode != 200 {
+		// Logins have no method, the endpoint names them.
+		name := strings.TrimSuffix(method, "/")
+		if name == "" {
+			name = key
+		}
+		return nil, newAPIError(name, res.StatusCode, data)
+	}
 
 	return data, nil
 }
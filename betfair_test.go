@@ -30,8 +30,13 @@ package betfair
 
 import (
 	"testing"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"time"
 )
 
 var (
@@ -39,9 +44,19 @@ var (
 	marketId 	string
 )
 
+// Tests against betfair need the session created by TestNewSession.
+func requireSession(t *testing.T) {
+	if s == nil {
+		t.Skip("No session, skipping test against betfair")
+	}
+}
+
 func TestNewSession(t *testing.T) {
 	// Get a local configuration for testing
 	file, err := os.Open("betfair_test.conf.json")
+	if os.IsNotExist(err) {
+		t.Skip("No betfair_test.conf.json, skipping tests against betfair")
+	}
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -59,18 +74,21 @@ func TestNewSession(t *testing.T) {
 }
 
 func TestLoginNonInteractive(t *testing.T) {
+	requireSession(t)
 	if err := s.LoginNonInteractive(); err != nil {
 		t.Error(err.Error())
 	}
 }
 
 func TestKeepAlive(t *testing.T) {
+	requireSession(t)
 	if err := s.KeepAlive(); err != nil {
 		t.Error(err.Error())
 	}	
 }
 
 func TestListCountries(t *testing.T) {
+	requireSession(t)
 	filter := new(MarketFilter)
 	res, err := s.ListCountries(filter)
 	if err != nil {
@@ -82,6 +100,7 @@ func TestListCountries(t *testing.T) {
 }
 
 func TestListCompetitions(t *testing.T) {
+	requireSession(t)
 	filter := new(MarketFilter)
 	res, err := s.ListCompetitions(filter)
 	if err != nil {
@@ -93,6 +112,7 @@ func TestListCompetitions(t *testing.T) {
 }
 
 func TestListEvents(t *testing.T) {
+	requireSession(t)
 	filter := new(MarketFilter)
 	res, err := s.ListEvents(filter)
 	if err != nil {
@@ -104,6 +124,7 @@ func TestListEvents(t *testing.T) {
 }
 
 func TestListEventTypes(t *testing.T) {
+	requireSession(t)
 	filter := new(MarketFilter)
 	res, err := s.ListEventTypes(filter)
 	if err != nil {
@@ -115,8 +136,9 @@ func TestListEventTypes(t *testing.T) {
 }
 
 func TestListMarketCatalogue(t *testing.T) {
+	requireSession(t)
 	filter := new(MarketFilter)
-	res, err := s.ListMarketCatalogue(filter, 10)
+	res, err := s.ListMarketCatalogue(filter, 10, new(ProjectionParams))
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -128,8 +150,9 @@ func TestListMarketCatalogue(t *testing.T) {
 }
 
 func TestListMarketBook(t *testing.T) {
+	requireSession(t)
 	marketIds := []string{marketId}
-	res, err := s.ListMarketBook(marketIds)
+	res, err := s.ListMarketBook(marketIds, new(ProjectionParams))
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -138,7 +161,49 @@ func TestListMarketBook(t *testing.T) {
 	}
 }
 
+func TestListMarketBookWithOptions(t *testing.T) {
+	requireSession(t)
+	marketIds := []string{marketId}
+	options := new(MarketBookOptions)
+	options.OrderProjection = OrderProjectionAll
+	options.PartitionMatchedByStrategyRef = true
+	options.MatchedSince = time.Now().Add(-time.Hour)
+	res, err := s.ListMarketBookWithOptions(marketIds, options)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if len(res) < 1 {
+		t.Error("Result is empty")
+	}
+}
+
+func TestListMarketProfitAndLoss(t *testing.T) {
+	requireSession(t)
+	marketIds := []string{marketId}
+	_, err := s.ListMarketProfitAndLoss(marketIds, false, false, false)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestListRunnerBook(t *testing.T) {
+	requireSession(t)
+	books, err := s.ListMarketBook([]string{marketId}, new(ProjectionParams))
+	if err != nil || len(books) < 1 || len(books[0].Runners) < 1 {
+		t.Skip("No runner available")
+	}
+	runner := books[0].Runners[0]
+	res, err := s.ListRunnerBook(marketId, runner.SelectionID, runner.Handicap, nil)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if len(res) != 1 || len(res[0].Runners) != 1 {
+		t.Error("Expected a single runner")
+	}
+}
+
 func TestListMarketTypes(t *testing.T) {
+	requireSession(t)
 	filter := new(MarketFilter)
 	filter.MarketIds = []string{marketId}
 	res, err := s.ListMarketTypes(filter)
@@ -150,7 +215,76 @@ func TestListMarketTypes(t *testing.T) {
 	}
 }
 
+func TestListTimeRanges(t *testing.T) {
+	requireSession(t)
+	filter := new(MarketFilter)
+	res, err := s.ListTimeRanges(filter, TimeGranularityDays)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if len(res) < 1 {
+		t.Error("Result is empty")
+	}
+}
+
+func TestListVenues(t *testing.T) {
+	requireSession(t)
+	filter := new(MarketFilter)
+	filter.EventTypeIds = []string{"7"}
+	res, err := s.ListVenues(filter)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if len(res) < 1 {
+		t.Error("Result is empty")
+	}
+}
+
+func TestListCurrentOrders(t *testing.T) {
+	requireSession(t)
+	_, err := s.ListCurrentOrders(nil)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestIterateCurrentOrders(t *testing.T) {
+	requireSession(t)
+	it := s.IterateCurrentOrders(&CurrentOrdersParams{RecordCount: 10})
+	for it.Next() {
+		if it.Order().BetId == "" {
+			t.Error("Order without betId")
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestListClearedOrders(t *testing.T) {
+	requireSession(t)
+	params := &ClearedOrdersParams{IncludeItemDescription: true, RecordCount: 10}
+	_, err := s.ListClearedOrders(BetStatusSettled, params)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestIterateClearedOrders(t *testing.T) {
+	requireSession(t)
+	it := s.IterateClearedOrders(BetStatusSettled, &ClearedOrdersParams{RecordCount: 10})
+	for it.Next() {
+		if it.Order().BetId == "" {
+			t.Error("Order without betId")
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
 func TestGetAccountDetails(t *testing.T) {
+	requireSession(t)
 	_, err := s.GetAccountDetails()
 	if err != nil {
 		t.Error(err.Error())
@@ -158,13 +292,24 @@ func TestGetAccountDetails(t *testing.T) {
 }
 
 func TestGetAccountFunds(t *testing.T) {
+	requireSession(t)
 	_, err := s.GetAccountFunds()
 	if err != nil {
 		t.Error(err.Error())
 	}	
 }
 
+func TestGetAccountStatement(t *testing.T) {
+	requireSession(t)
+	params := &AccountStatementParams{IncludeItem: IncludeItemExchange, RecordCount: 10}
+	_, err := s.GetAccountStatement(params)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
 func TestGetDeveloperAppKeys(t *testing.T) {
+	requireSession(t)
 	_, err := s.GetDeveloperAppKeys()
 	if err != nil {
 		t.Error(err.Error())
@@ -172,7 +317,69 @@ func TestGetDeveloperAppKeys(t *testing.T) {
 }
 
 func TestLogout(t *testing.T) {
+	requireSession(t)
 	if err := s.Logout(); err != nil {
 		t.Error(err.Error())
 	}	
 }
+
+func TestNewAPIError(t *testing.T) {
+	body := []byte(`{"faultcode":"Client","faultstring":"ANGX-0003","detail":{"APINGException":{"requestUUID":"prdang001-1","errorCode":"INVALID_SESSION_INFORMATION","errorDetails":""},"exceptionname":"APINGException"}}`)
+	err := newAPIError("listMarketBook", 400, body)
+	if err.ErrorCode != ErrorCodeInvalidSessionInformation {
+		t.Error("Unexpected error code: " + string(err.ErrorCode))
+	}
+	if err.RequestUUID != "prdang001-1" || err.Message != "ANGX-0003" {
+		t.Error("Unexpected error: " + err.Error())
+	}
+	body = []byte(`{"faultcode":"Client","faultstring":"AANGX-0010","detail":{"AccountAPINGException":{"requestUUID":"prdaan001-2","errorCode":"NO_SESSION","errorDetails":"No session"},"exceptionname":"AccountAPINGException"}}`)
+	err = newAPIError("getAccountFunds", 400, body)
+	if err.ErrorCode != ErrorCodeNoSession || err.RequestUUID != "prdaan001-2" || err.Message != "No session" {
+		t.Error("Unexpected error: " + err.Error())
+	}
+	err = newAPIError("listMarketBook", 503, []byte("<html></html>"))
+	if err.ErrorCode != "" || err.StatusCode != 503 {
+		t.Error("Unexpected error: " + err.Error())
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	config := &Config{MaxIdleConnsPerHost: 8, ProxyURL: "http://proxy.example.com:3128"}
+	transport, err := newTransport(config, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if transport.MaxIdleConnsPerHost != 8 {
+		t.Error("MaxIdleConnsPerHost not applied")
+	}
+	if transport.Proxy == nil {
+		t.Error("Proxy not applied")
+	}
+	if _, err := newTransport(&Config{ProxyURL: "://"}, nil); err == nil {
+		t.Error("Expected an error for an invalid proxy")
+	}
+}
+
+func TestVerifyPins(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	cert := server.Certificate()
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(hash[:])
+
+	ssl, err := newTLSConfig(&Config{PinnedSPKI: []string{pin}}, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := ssl.VerifyPeerCertificate([][]byte{cert.Raw}, nil); err != nil {
+		t.Error(err.Error())
+	}
+	other := sha256.Sum256([]byte("other"))
+	pins := map[string]bool{string(other[:]): true}
+	if err := verifyPins(pins, [][]byte{cert.Raw}, nil); err == nil {
+		t.Error("Expected an error for an unpinned certificate")
+	}
+	if _, err := newTLSConfig(&Config{PinnedSPKI: []string{"short"}}, nil); err == nil {
+		t.Error("Expected an error for an invalid pin")
+	}
+}
@@ -27,7 +27,10 @@
 package betfair
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 	// "log"
@@ -62,6 +65,45 @@ type PersistenceTypeVal baseEnumVal
 // SideVal Enum of side, back or lay
 type SideVal baseEnumVal
 
+// TimeInForceVal Enum of time in force values for limit orders
+type TimeInForceVal baseEnumVal
+
+// BetTargetTypeVal Enum of bet target types for limit orders
+type BetTargetTypeVal baseEnumVal
+
+// ExecutionReportStatusVal Enum of the overall status of an order operation
+type ExecutionReportStatusVal baseEnumVal
+
+// ExecutionReportErrorCodeVal Enum of errors for a whole order operation
+type ExecutionReportErrorCodeVal baseEnumVal
+
+// InstructionReportStatusVal Enum of the status of a single instruction
+type InstructionReportStatusVal baseEnumVal
+
+// InstructionReportErrorCodeVal Enum of errors for a single instruction
+type InstructionReportErrorCodeVal baseEnumVal
+
+// RollupModelVal Enum of rollup models for best offers
+type RollupModelVal baseEnumVal
+
+// MarketBettingTypeVal Enum of market betting types
+type MarketBettingTypeVal baseEnumVal
+
+// TimeGranularityVal Enum of granularities for time ranges
+type TimeGranularityVal baseEnumVal
+
+// OrderByVal Enum of orderings for order listings
+type OrderByVal baseEnumVal
+
+// SortDirVal Enum of sort directions for order listings
+type SortDirVal baseEnumVal
+
+// BetStatusVal Enum of statuses of cleared bets
+type BetStatusVal baseEnumVal
+
+// GroupByVal Enum of aggregation levels for cleared orders
+type GroupByVal baseEnumVal
+
 // Constants for side, back or lay
 const (
 	SideBack SideVal = "BACK"
@@ -79,6 +121,8 @@ const (
 const (
 	OrderStatusExecutionComplete OrderStatusVal = OrderProjectionExecutionComplete
 	OrderStatusExecutable                       = OrderProjectionExecutable
+	OrderStatusPending                          = "PENDING"
+	OrderStatusExpired                          = "EXPIRED"
 )
 
 // Constant values for use in match projections
@@ -132,6 +176,148 @@ const (
 	OrderTypeMarketOnClose              = "MARKET_ON_CLOSE"
 )
 
+// Constant values for rollup models of best offers
+const (
+	RollupModelStake            RollupModelVal = "STAKE"
+	RollupModelPayout                          = "PAYOUT"
+	RollupModelManagedLiability                = "MANAGED_LIABILITY"
+	RollupModelNone                            = "NONE"
+)
+
+// Constant values for market betting types
+const (
+	MarketBettingTypeOdds                    MarketBettingTypeVal = "ODDS"
+	MarketBettingTypeLine                                         = "LINE"
+	MarketBettingTypeRange                                        = "RANGE"
+	MarketBettingTypeAsianHandicapDoubleLine                      = "ASIAN_HANDICAP_DOUBLE_LINE"
+	MarketBettingTypeAsianHandicapSingleLine                      = "ASIAN_HANDICAP_SINGLE_LINE"
+	MarketBettingTypeFixedOdds                                    = "FIXED_ODDS"
+)
+
+// Constant values for granularity of time ranges
+const (
+	TimeGranularityDays    TimeGranularityVal = "DAYS"
+	TimeGranularityHours                      = "HOURS"
+	TimeGranularityMinutes                    = "MINUTES"
+)
+
+// Constant values for ordering of order listings
+const (
+	OrderByBet         OrderByVal = "BY_BET"
+	OrderByMarket                 = "BY_MARKET"
+	OrderByMatchTime              = "BY_MATCH_TIME"
+	OrderByPlaceTime              = "BY_PLACE_TIME"
+	OrderBySettledTime            = "BY_SETTLED_TIME"
+	OrderByVoidTime               = "BY_VOID_TIME"
+)
+
+// Constant values for sort direction of order listings
+const (
+	SortDirEarliestToLatest SortDirVal = "EARLIEST_TO_LATEST"
+	SortDirLatestToEarliest            = "LATEST_TO_EARLIEST"
+)
+
+// Constant values for the status of cleared bets
+const (
+	BetStatusSettled   BetStatusVal = "SETTLED"
+	BetStatusVoided                 = "VOIDED"
+	BetStatusLapsed                 = "LAPSED"
+	BetStatusCancelled              = "CANCELLED"
+)
+
+// Constant values for grouping of cleared orders
+const (
+	GroupByEventType GroupByVal = "EVENT_TYPE"
+	GroupByEvent                = "EVENT"
+	GroupByMarket               = "MARKET"
+	GroupByRunner               = "RUNNER"
+	GroupBySide                 = "SIDE"
+	GroupByBet                  = "BET"
+)
+
+// Constant values for time in force of limit orders
+const (
+	TimeInForceFillOrKill TimeInForceVal = "FILL_OR_KILL"
+)
+
+// Constant values for bet target types of limit orders
+const (
+	BetTargetTypeBackersProfit BetTargetTypeVal = "BACKERS_PROFIT"
+	BetTargetTypePayout                         = "PAYOUT"
+)
+
+// Constant values for the status of an execution report
+const (
+	ExecutionReportStatusSuccess             ExecutionReportStatusVal = "SUCCESS"
+	ExecutionReportStatusFailure                                      = "FAILURE"
+	ExecutionReportStatusProcessedWithErrors                          = "PROCESSED_WITH_ERRORS"
+	ExecutionReportStatusTimeout                                      = "TIMEOUT"
+)
+
+// Constant values for the error code of an execution report
+const (
+	ExecutionReportErrorErrorInMatcher          ExecutionReportErrorCodeVal = "ERROR_IN_MATCHER"
+	ExecutionReportErrorProcessedWithErrors                                 = "PROCESSED_WITH_ERRORS"
+	ExecutionReportErrorBetActionError                                      = "BET_ACTION_ERROR"
+	ExecutionReportErrorInvalidAccountState                                 = "INVALID_ACCOUNT_STATE"
+	ExecutionReportErrorInvalidWalletStatus                                 = "INVALID_WALLET_STATUS"
+	ExecutionReportErrorInsufficientFunds                                   = "INSUFFICIENT_FUNDS"
+	ExecutionReportErrorLossLimitExceeded                                   = "LOSS_LIMIT_EXCEEDED"
+	ExecutionReportErrorMarketSuspended                                     = "MARKET_SUSPENDED"
+	ExecutionReportErrorMarketNotOpenForBetting                             = "MARKET_NOT_OPEN_FOR_BETTING"
+	ExecutionReportErrorDuplicateTransaction                                = "DUPLICATE_TRANSACTION"
+	ExecutionReportErrorInvalidOrder                                        = "INVALID_ORDER"
+	ExecutionReportErrorInvalidMarketId                                     = "INVALID_MARKET_ID"
+	ExecutionReportErrorPermissionDenied                                    = "PERMISSION_DENIED"
+	ExecutionReportErrorDuplicateBetIds                                     = "DUPLICATE_BETIDS"
+	ExecutionReportErrorNoActionRequired                                    = "NO_ACTION_REQUIRED"
+	ExecutionReportErrorServiceUnavailable                                  = "SERVICE_UNAVAILABLE"
+	ExecutionReportErrorRejectedByRegulator                                 = "REJECTED_BY_REGULATOR"
+	ExecutionReportErrorNoChasing                                           = "NO_CHASING"
+	ExecutionReportErrorRegulatorIsNotAvailable                             = "REGULATOR_IS_NOT_AVAILABLE"
+	ExecutionReportErrorTooManyInstructions                                 = "TOO_MANY_INSTRUCTIONS"
+	ExecutionReportErrorInvalidMarketVersion                                = "INVALID_MARKET_VERSION"
+	ExecutionReportErrorInvalidProfitRatio                                  = "INVALID_PROFIT_RATIO"
+)
+
+// Constant values for the status of an instruction report
+const (
+	InstructionReportStatusSuccess InstructionReportStatusVal = "SUCCESS"
+	InstructionReportStatusFailure                            = "FAILURE"
+	InstructionReportStatusTimeout                            = "TIMEOUT"
+)
+
+// Constant values for the error code of an instruction report
+const (
+	InstructionReportErrorInvalidBetSize                    InstructionReportErrorCodeVal = "INVALID_BET_SIZE"
+	InstructionReportErrorInvalidRunner                                                   = "INVALID_RUNNER"
+	InstructionReportErrorBetTakenOrLapsed                                                = "BET_TAKEN_OR_LAPSED"
+	InstructionReportErrorBetInProgress                                                   = "BET_IN_PROGRESS"
+	InstructionReportErrorRunnerRemoved                                                   = "RUNNER_REMOVED"
+	InstructionReportErrorMarketNotOpenForBetting                                         = "MARKET_NOT_OPEN_FOR_BETTING"
+	InstructionReportErrorLossLimitExceeded                                               = "LOSS_LIMIT_EXCEEDED"
+	InstructionReportErrorMarketNotOpenForBspBetting                                      = "MARKET_NOT_OPEN_FOR_BSP_BETTING"
+	InstructionReportErrorInvalidPriceEdit                                                = "INVALID_PRICE_EDIT"
+	InstructionReportErrorInvalidOdds                                                     = "INVALID_ODDS"
+	InstructionReportErrorInsufficientFunds                                               = "INSUFFICIENT_FUNDS"
+	InstructionReportErrorInvalidPersistenceType                                          = "INVALID_PERSISTENCE_TYPE"
+	InstructionReportErrorErrorInMatcher                                                  = "ERROR_IN_MATCHER"
+	InstructionReportErrorInvalidBackLayCombination                                       = "INVALID_BACK_LAY_COMBINATION"
+	InstructionReportErrorErrorInOrder                                                    = "ERROR_IN_ORDER"
+	InstructionReportErrorInvalidBidType                                                  = "INVALID_BID_TYPE"
+	InstructionReportErrorInvalidBetId                                                    = "INVALID_BET_ID"
+	InstructionReportErrorCancelledNotPlaced                                              = "CANCELLED_NOT_PLACED"
+	InstructionReportErrorRelatedActionFailed                                             = "RELATED_ACTION_FAILED"
+	InstructionReportErrorNoActionRequired                                                = "NO_ACTION_REQUIRED"
+	InstructionReportErrorTimeInForceConflict                                             = "TIME_IN_FORCE_CONFLICT"
+	InstructionReportErrorUnexpectedPersistenceType                                       = "UNEXPECTED_PERSISTENCE_TYPE"
+	InstructionReportErrorInvalidOrderType                                                = "INVALID_ORDER_TYPE"
+	InstructionReportErrorUnexpectedMinFillSize                                           = "UNEXPECTED_MIN_FILL_SIZE"
+	InstructionReportErrorInvalidCustomerOrderRef                                         = "INVALID_CUSTOMER_ORDER_REF"
+	InstructionReportErrorInvalidMinFillSize                                              = "INVALID_MIN_FILL_SIZE"
+	InstructionReportErrorBetLapsedPriceImprovementTooLarge                               = "BET_LAPSED_PRICE_IMPROVEMENT_TOO_LARGE"
+)
+
 // ProjectionParams contains the various projections
 // for assigning to requests
 type ProjectionParams struct {
@@ -141,33 +327,108 @@ type ProjectionParams struct {
 	MatchProjection  MatchProjVal
 }
 
-// MarketFilter allows various filtering of market types
+// MarketFilter allows various filtering of market types. The boolean
+// filters are pointers so that false can be sent explicitly, see BoolPtr.
 type MarketFilter struct {
-	TextQuery       string   `json:"textQuery,omitempty"`
-	ExchangeIds     []string `json:"exchangeIds,omitempty"`
-	EventTypeIds    []string `json:"eventTypeIds,omitempty"`
-	EventIds        []string `json:"eventIds,omitempty"`
-	CompetitionIds  []string `json:"competitionIds,omitempty"`
-	MarketCountries []string `json:"marketCountries,omitempty"`
-	MarketIds       []string `json:"marketIds,omitempty"`
-	MarketTypeCodes []string `json:"marketTypeCodes,omitempty"`
+	TextQuery          string                 `json:"textQuery,omitempty"`
+	ExchangeIds        []string               `json:"exchangeIds,omitempty"`
+	EventTypeIds       []string               `json:"eventTypeIds,omitempty"`
+	EventIds           []string               `json:"eventIds,omitempty"`
+	CompetitionIds     []string               `json:"competitionIds,omitempty"`
+	MarketIds          []string               `json:"marketIds,omitempty"`
+	Venues             []string               `json:"venues,omitempty"`
+	BspOnly            *bool                  `json:"bspOnly,omitempty"`
+	TurnInPlayEnabled  *bool                  `json:"turnInPlayEnabled,omitempty"`
+	InPlayOnly         *bool                  `json:"inPlayOnly,omitempty"`
+	MarketBettingTypes []MarketBettingTypeVal `json:"marketBettingTypes,omitempty"`
+	MarketCountries    []string               `json:"marketCountries,omitempty"`
+	MarketTypeCodes    []string               `json:"marketTypeCodes,omitempty"`
+	MarketStartTime    *TimeRange             `json:"marketStartTime,omitempty"`
+	WithOrders         []OrderStatusVal       `json:"withOrders,omitempty"`
+	RaceTypes          []string               `json:"raceTypes,omitempty"`
+}
+
+// BoolPtr returns a pointer to b, for the optional boolean filters.
+func BoolPtr(b bool) *bool {
+	return &b
+}
+
+// TimeRange Range of time, unset bounds are left out of requests
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// MarshalJSON omits zero bounds, which betfair would otherwise take as
+// the year 1.
+func (r TimeRange) MarshalJSON() ([]byte, error) {
+	v := struct {
+		From *time.Time `json:"from,omitempty"`
+		To   *time.Time `json:"to,omitempty"`
+	}{}
+	if !r.From.IsZero() {
+		v.From = &r.From
+	}
+	if !r.To.IsZero() {
+		v.To = &r.To
+	}
+	return json.Marshal(v)
+}
+
+// ExBestOffersOverrides Options to alter the default representation of best
+// offer prices
+type ExBestOffersOverrides struct {
+	BestPricesDepth          int            `json:"bestPricesDepth,omitempty"`
+	RollupModel              RollupModelVal `json:"rollupModel,omitempty"`
+	RollupLimit              int            `json:"rollupLimit,omitempty"`
+	RollupLiabilityThreshold float32        `json:"rollupLiabilityThreshold,omitempty"`
+	RollupLiabilityFactor    int            `json:"rollupLiabilityFactor,omitempty"`
 }
 
 // PriceProjection sets data returned from price queries
 type PriceProjection struct {
-	PriceData []PriceDataVal `json:"priceData,omitempty"`
+	PriceData             []PriceDataVal         `json:"priceData,omitempty"`
+	ExBestOffersOverrides *ExBestOffersOverrides `json:"exBestOffersOverrides,omitempty"`
+	Virtualise            bool                   `json:"virtualise,omitempty"`
+	RolloverStakes        bool                   `json:"rolloverStakes,omitempty"`
 }
 
 // Params sets up the required parameters for betfair requests
 type Params struct {
-	MarketFilter     *MarketFilter    `json:"filter,omitempty"`
-	MarketIds        []string         `json:"marketIds,omitempty"`
-	PriceProjection  *PriceProjection `json:"priceProjection,omitempty"`
-	MarketProjection []MarketProjVal  `json:"marketProjection,omitempty"`
-	OrderProjection  OrderProjVal     `json:"orderProjection,omitempty"`
-	MatchProjection  MatchProjVal     `json:"matchProjection,omitempty"`
-	MaxResults       int              `json:"maxResults,omitempty"`
-	Locale           string           `json:"locale,omitempty"`
+	MarketFilter                  *MarketFilter      `json:"filter,omitempty"`
+	MarketIds                     []string           `json:"marketIds,omitempty"`
+	MarketId                      string             `json:"marketId,omitempty"`
+	SelectionId                   uint32             `json:"selectionId,omitempty"`
+	Handicap                      float32            `json:"handicap,omitempty"`
+	PriceProjection               *PriceProjection   `json:"priceProjection,omitempty"`
+	MarketProjection              []MarketProjVal    `json:"marketProjection,omitempty"`
+	OrderProjection               OrderProjVal       `json:"orderProjection,omitempty"`
+	MatchProjection               MatchProjVal       `json:"matchProjection,omitempty"`
+	MaxResults                    int                `json:"maxResults,omitempty"`
+	Granularity                   TimeGranularityVal `json:"granularity,omitempty"`
+	IncludeSettledBets            bool               `json:"includeSettledBets,omitempty"`
+	IncludeBspBets                bool               `json:"includeBspBets,omitempty"`
+	NetOfCommission               bool               `json:"netOfCommission,omitempty"`
+	IncludeOverallPosition        *bool              `json:"includeOverallPosition,omitempty"`
+	PartitionMatchedByStrategyRef bool               `json:"partitionMatchedByStrategyRef,omitempty"`
+	CustomerStrategyRefs          []string           `json:"customerStrategyRefs,omitempty"`
+	CurrencyCode                  string             `json:"currencyCode,omitempty"`
+	MatchedSince                  *time.Time         `json:"matchedSince,omitempty"`
+	BetIds                        []string           `json:"betIds,omitempty"`
+	Locale                        string             `json:"locale,omitempty"`
+}
+
+// MarketBookOptions contains the projections and every other optional
+// parameter of market book requests. IncludeOverallPosition defaults to
+// true on betfair side, so it is a pointer to allow sending false.
+type MarketBookOptions struct {
+	ProjectionParams
+	IncludeOverallPosition        *bool
+	PartitionMatchedByStrategyRef bool
+	CustomerStrategyRefs          []string
+	CurrencyCode                  string
+	MatchedSince                  time.Time
+	BetIds                        []string
 }
 
 // SetProjections applies the projections from a param object to the general
@@ -179,6 +440,21 @@ func (p *Params) SetProjections(params *ProjectionParams) {
 	p.MatchProjection = params.MatchProjection
 }
 
+// SetMarketBookOptions applies the projections and options of market book
+// requests to the general params object
+func (p *Params) SetMarketBookOptions(options *MarketBookOptions) {
+	p.SetProjections(&options.ProjectionParams)
+	p.IncludeOverallPosition = options.IncludeOverallPosition
+	p.PartitionMatchedByStrategyRef = options.PartitionMatchedByStrategyRef
+	p.CustomerStrategyRefs = options.CustomerStrategyRefs
+	p.CurrencyCode = options.CurrencyCode
+	if !options.MatchedSince.IsZero() {
+		matchedSince := options.MatchedSince
+		p.MatchedSince = &matchedSince
+	}
+	p.BetIds = options.BetIds
+}
+
 // EventType represents the name and betfair ID of events
 type EventType struct {
 	ID   string
@@ -221,6 +497,18 @@ type EventResult struct {
 	MarketCount int
 }
 
+// TimeRangeResult Time range with the number of markets starting in it
+type TimeRangeResult struct {
+	TimeRange   TimeRange
+	MarketCount int
+}
+
+// VenueResult Venue with the number of associated markets
+type VenueResult struct {
+	Venue       string
+	MarketCount int
+}
+
 // PriceSize gives price and size of stake
 type PriceSize struct {
 	Price float32 `json:"price,omitempty"`
@@ -293,6 +581,20 @@ type MarketBook struct {
 	Runners               []Runner
 }
 
+// ErrMarketDataDelayed is returned by RequireLiveData for delayed books.
+var ErrMarketDataDelayed = errors.New("Market data is delayed")
+
+// RequireLiveData returns an error wrapping ErrMarketDataDelayed if any of
+// the books is delayed, so that strategies can refuse to trade on them.
+func RequireLiveData(books []MarketBook) error {
+	for _, book := range books {
+		if book.IsMarketDataDelayed {
+			return fmt.Errorf("%w: %s", ErrMarketDataDelayed, book.MarketId)
+		}
+	}
+	return nil
+}
+
 // Runner Details for each runner, containing current bets
 type Runner struct {
 	SelectionID      uint32          `json:"selectionId,omitempty"`
@@ -349,49 +651,479 @@ type MarketDescription struct {
 	Clarifications     string
 }
 
+// RunnerProfitAndLoss Profit and loss if the runner wins or loses
+type RunnerProfitAndLoss struct {
+	SelectionId uint32  `json:"selectionId"`
+	IfWin       float32 `json:"ifWin"`
+	IfLose      float32 `json:"ifLose"`
+	IfPlace     float32 `json:"ifPlace"`
+}
+
+// MarketProfitAndLoss Profit and loss in a market
+type MarketProfitAndLoss struct {
+	MarketId          string                `json:"marketId"`
+	CommissionApplied float32               `json:"commissionApplied"`
+	ProfitAndLosses   []RunnerProfitAndLoss `json:"profitAndLosses"`
+}
+
 // MarketType Result.
 type MarketTypeResult struct {
 	MarketType  string
 	MarketCount int
 }
 
+// LimitOrder Place a new LIMIT order (simple exchange bet for immediate execution)
+type LimitOrder struct {
+	Size            float32            `json:"size,omitempty"`
+	Price           float32            `json:"price"`
+	PersistenceType PersistenceTypeVal `json:"persistenceType,omitempty"`
+	TimeInForce     TimeInForceVal     `json:"timeInForce,omitempty"`
+	MinFillSize     float32            `json:"minFillSize,omitempty"`
+	BetTargetType   BetTargetTypeVal   `json:"betTargetType,omitempty"`
+	BetTargetSize   float32            `json:"betTargetSize,omitempty"`
+}
+
+// LimitOnCloseOrder Place a new LIMIT_ON_CLOSE bet
+type LimitOnCloseOrder struct {
+	Liability float32 `json:"liability"`
+	Price     float32 `json:"price"`
+}
+
+// MarketOnCloseOrder Place a new MARKET_ON_CLOSE bet
+type MarketOnCloseOrder struct {
+	Liability float32 `json:"liability"`
+}
+
+// PlaceInstruction Instruction to place a new order. Only the order payload
+// matching OrderType has to be set.
+type PlaceInstruction struct {
+	OrderType          OrderTypeVal        `json:"orderType"`
+	SelectionId        uint32              `json:"selectionId"`
+	Handicap           float32             `json:"handicap,omitempty"`
+	Side               SideVal             `json:"side"`
+	LimitOrder         *LimitOrder         `json:"limitOrder,omitempty"`
+	LimitOnCloseOrder  *LimitOnCloseOrder  `json:"limitOnCloseOrder,omitempty"`
+	MarketOnCloseOrder *MarketOnCloseOrder `json:"marketOnCloseOrder,omitempty"`
+	CustomerOrderRef   string              `json:"customerOrderRef,omitempty"`
+}
+
+// MarketVersion Version of a market, used to reject orders placed against
+// a newer version of the market.
+type MarketVersion struct {
+	Version int `json:"version"`
+}
+
+// PlaceInstructionReport Response to a single PlaceInstruction
+type PlaceInstructionReport struct {
+	Status              InstructionReportStatusVal    `json:"status"`
+	ErrorCode           InstructionReportErrorCodeVal `json:"errorCode,omitempty"`
+	OrderStatus         OrderStatusVal                `json:"orderStatus,omitempty"`
+	Instruction         PlaceInstruction              `json:"instruction"`
+	BetId               string                        `json:"betId,omitempty"`
+	PlacedDate          time.Time                     `json:"placedDate,omitempty"`
+	AveragePriceMatched float32                       `json:"averagePriceMatched,omitempty"`
+	SizeMatched         float32                       `json:"sizeMatched,omitempty"`
+}
+
+// PlaceExecutionReport Response of a placeOrders operation
+type PlaceExecutionReport struct {
+	CustomerRef        string                      `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatusVal    `json:"status"`
+	ErrorCode          ExecutionReportErrorCodeVal `json:"errorCode,omitempty"`
+	MarketId           string                      `json:"marketId"`
+	InstructionReports []PlaceInstructionReport    `json:"instructionReports"`
+}
+
+// CancelInstruction Instruction to fully or partially cancel an order. A zero
+// SizeReduction cancels the whole remaining size.
+type CancelInstruction struct {
+	BetId         string  `json:"betId"`
+	SizeReduction float32 `json:"sizeReduction,omitempty"`
+}
+
+// CancelInstructionReport Response to a single CancelInstruction
+type CancelInstructionReport struct {
+	Status        InstructionReportStatusVal    `json:"status"`
+	ErrorCode     InstructionReportErrorCodeVal `json:"errorCode,omitempty"`
+	Instruction   *CancelInstruction            `json:"instruction,omitempty"`
+	SizeCancelled float32                       `json:"sizeCancelled"`
+	CancelledDate time.Time                     `json:"cancelledDate,omitempty"`
+}
+
+// CancelExecutionReport Response of a cancelOrders operation
+type CancelExecutionReport struct {
+	CustomerRef        string                      `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatusVal    `json:"status"`
+	ErrorCode          ExecutionReportErrorCodeVal `json:"errorCode,omitempty"`
+	MarketId           string                      `json:"marketId,omitempty"`
+	InstructionReports []CancelInstructionReport   `json:"instructionReports,omitempty"`
+}
+
+// ReplaceInstruction Instruction to replace a LIMIT order at a new price.
+type ReplaceInstruction struct {
+	BetId    string  `json:"betId"`
+	NewPrice float32 `json:"newPrice"`
+}
+
+// ReplaceInstructionReport Response to a single ReplaceInstruction, made of
+// the report of the cancellation and of the new order placement
+type ReplaceInstructionReport struct {
+	Status                  InstructionReportStatusVal    `json:"status"`
+	ErrorCode               InstructionReportErrorCodeVal `json:"errorCode,omitempty"`
+	CancelInstructionReport *CancelInstructionReport      `json:"cancelInstructionReport,omitempty"`
+	PlaceInstructionReport  *PlaceInstructionReport       `json:"placeInstructionReport,omitempty"`
+}
+
+// ReplaceExecutionReport Response of a replaceOrders operation
+type ReplaceExecutionReport struct {
+	CustomerRef        string                      `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatusVal    `json:"status"`
+	ErrorCode          ExecutionReportErrorCodeVal `json:"errorCode,omitempty"`
+	MarketId           string                      `json:"marketId"`
+	InstructionReports []ReplaceInstructionReport  `json:"instructionReports"`
+}
+
+// UpdateInstruction Instruction to update the persistence type of a LIMIT
+// order.
+type UpdateInstruction struct {
+	BetId              string             `json:"betId"`
+	NewPersistenceType PersistenceTypeVal `json:"newPersistenceType"`
+}
+
+// UpdateInstructionReport Response to a single UpdateInstruction
+type UpdateInstructionReport struct {
+	Status      InstructionReportStatusVal    `json:"status"`
+	ErrorCode   InstructionReportErrorCodeVal `json:"errorCode,omitempty"`
+	Instruction UpdateInstruction             `json:"instruction"`
+}
+
+// UpdateExecutionReport Response of an updateOrders operation
+type UpdateExecutionReport struct {
+	CustomerRef        string                      `json:"customerRef,omitempty"`
+	Status             ExecutionReportStatusVal    `json:"status"`
+	ErrorCode          ExecutionReportErrorCodeVal `json:"errorCode,omitempty"`
+	MarketId           string                      `json:"marketId"`
+	InstructionReports []UpdateInstructionReport   `json:"instructionReports"`
+}
+
+// CurrentOrdersParams sets up the parameters for listing current orders.
+// All fields are optional; RecordCount is the page size, up to 1000.
+type CurrentOrdersParams struct {
+	BetIds               []string     `json:"betIds,omitempty"`
+	MarketIds            []string     `json:"marketIds,omitempty"`
+	OrderProjection      OrderProjVal `json:"orderProjection,omitempty"`
+	CustomerOrderRefs    []string     `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs []string     `json:"customerStrategyRefs,omitempty"`
+	DateRange            *TimeRange   `json:"dateRange,omitempty"`
+	OrderBy              OrderByVal   `json:"orderBy,omitempty"`
+	SortDir              SortDirVal   `json:"sortDir,omitempty"`
+	FromRecord           int          `json:"fromRecord,omitempty"`
+	RecordCount          int          `json:"recordCount,omitempty"`
+}
+
+// CurrentOrderSummary Summary of a current order
+type CurrentOrderSummary struct {
+	BetId               string             `json:"betId"`
+	MarketId            string             `json:"marketId"`
+	SelectionId         uint32             `json:"selectionId"`
+	Handicap            float32            `json:"handicap"`
+	PriceSize           PriceSize          `json:"priceSize"`
+	BspLiability        float32            `json:"bspLiability"`
+	Side                SideVal            `json:"side"`
+	Status              OrderStatusVal     `json:"status"`
+	PersistenceType     PersistenceTypeVal `json:"persistenceType"`
+	OrderType           OrderTypeVal       `json:"orderType"`
+	PlacedDate          time.Time          `json:"placedDate"`
+	MatchedDate         time.Time          `json:"matchedDate"`
+	AveragePriceMatched float32            `json:"averagePriceMatched"`
+	SizeMatched         float32            `json:"sizeMatched"`
+	SizeRemaining       float32            `json:"sizeRemaining"`
+	SizeLapsed          float32            `json:"sizeLapsed"`
+	SizeCancelled       float32            `json:"sizeCancelled"`
+	SizeVoided          float32            `json:"sizeVoided"`
+	RegulatorAuthCode   string             `json:"regulatorAuthCode"`
+	RegulatorCode       string             `json:"regulatorCode"`
+	CustomerOrderRef    string             `json:"customerOrderRef"`
+	CustomerStrategyRef string             `json:"customerStrategyRef"`
+}
+
+// CurrentOrderSummaryReport A container representing search results
+type CurrentOrderSummaryReport struct {
+	CurrentOrders []CurrentOrderSummary `json:"currentOrders"`
+	MoreAvailable bool                  `json:"moreAvailable"`
+}
+
+// Pages through the results of a request following moreAvailable. It is
+// embedded in the iterators, which keep the items of the current page.
+type pager struct {
+	from    int
+	pending int
+	more    bool
+	started bool
+	err     error
+}
+
+// Advances to the next item, calling fetch with the index of the first
+// record when the current page is exhausted. fetch returns the number of
+// items of the page and whether more are available.
+func (p *pager) next(fetch func(from int) (int, bool, error)) bool {
+	for p.pending == 0 {
+		if p.err != nil || (p.started && !p.more) {
+			return false
+		}
+		n, more, err := fetch(p.from)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.started = true
+		p.pending = n
+		p.more = more && n > 0
+		p.from += n
+	}
+	p.pending--
+	return true
+}
+
+// CurrentOrdersIterator walks through every current order matching some
+// parameters, requesting the next page whenever more orders are available.
+type CurrentOrdersIterator struct {
+	pager
+	ctx     context.Context
+	s       *Session
+	params  CurrentOrdersParams
+	orders  []CurrentOrderSummary
+	current CurrentOrderSummary
+}
+
+// Next advances to the next order, returning false when there are no more
+// orders or a request failed.
+func (it *CurrentOrdersIterator) Next() bool {
+	if !it.next(it.fetch) {
+		return false
+	}
+	it.current, it.orders = it.orders[0], it.orders[1:]
+	return true
+}
+
+func (it *CurrentOrdersIterator) fetch(from int) (int, bool, error) {
+	it.params.FromRecord = from
+	report, err := it.s.ListCurrentOrdersContext(it.ctx, &it.params)
+	it.orders = report.CurrentOrders
+	return len(report.CurrentOrders), report.MoreAvailable, err
+}
+
+// Order returns the order the iterator is positioned on.
+func (it *CurrentOrdersIterator) Order() CurrentOrderSummary {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *CurrentOrdersIterator) Err() error {
+	return it.err
+}
+
+// RunnerId identifies a runner of a market
+type RunnerId struct {
+	MarketId    string  `json:"marketId"`
+	SelectionId uint32  `json:"selectionId"`
+	Handicap    float32 `json:"handicap,omitempty"`
+}
+
+// ClearedOrdersParams sets up the parameters for listing cleared orders.
+// All fields are optional; RecordCount is the page size, up to 1000.
+type ClearedOrdersParams struct {
+	EventTypeIds           []string   `json:"eventTypeIds,omitempty"`
+	EventIds               []string   `json:"eventIds,omitempty"`
+	MarketIds              []string   `json:"marketIds,omitempty"`
+	RunnerIds              []RunnerId `json:"runnerIds,omitempty"`
+	BetIds                 []string   `json:"betIds,omitempty"`
+	CustomerOrderRefs      []string   `json:"customerOrderRefs,omitempty"`
+	CustomerStrategyRefs   []string   `json:"customerStrategyRefs,omitempty"`
+	Side                   SideVal    `json:"side,omitempty"`
+	SettledDateRange       *TimeRange `json:"settledDateRange,omitempty"`
+	GroupBy                GroupByVal `json:"groupBy,omitempty"`
+	IncludeItemDescription bool       `json:"includeItemDescription,omitempty"`
+	FromRecord             int        `json:"fromRecord,omitempty"`
+	RecordCount            int        `json:"recordCount,omitempty"`
+}
+
+type clearedOrdersParams struct {
+	BetStatus BetStatusVal `json:"betStatus"`
+	Locale    string       `json:"locale,omitempty"`
+	*ClearedOrdersParams
+}
+
+// ItemDescription Description of a cleared item, in the requested locale
+type ItemDescription struct {
+	EventTypeDesc   string    `json:"eventTypeDesc"`
+	EventDesc       string    `json:"eventDesc"`
+	MarketDesc      string    `json:"marketDesc"`
+	MarketType      string    `json:"marketType"`
+	MarketStartTime time.Time `json:"marketStartTime"`
+	RunnerDesc      string    `json:"runnerDesc"`
+	NumberOfWinners int       `json:"numberOfWinners"`
+	EachWayDivisor  float32   `json:"eachWayDivisor"`
+}
+
+// ClearedOrderSummary Summary of a cleared order, or of a group of them
+// depending on the requested GroupBy
+type ClearedOrderSummary struct {
+	EventTypeId         string             `json:"eventTypeId"`
+	EventId             string             `json:"eventId"`
+	MarketId            string             `json:"marketId"`
+	SelectionId         uint32             `json:"selectionId"`
+	Handicap            float32            `json:"handicap"`
+	BetId               string             `json:"betId"`
+	PlacedDate          time.Time          `json:"placedDate"`
+	PersistenceType     PersistenceTypeVal `json:"persistenceType"`
+	OrderType           OrderTypeVal       `json:"orderType"`
+	Side                SideVal            `json:"side"`
+	ItemDescription     *ItemDescription   `json:"itemDescription"`
+	BetOutcome          string             `json:"betOutcome"`
+	PriceRequested      float32            `json:"priceRequested"`
+	SettledDate         time.Time          `json:"settledDate"`
+	LastMatchedDate     time.Time          `json:"lastMatchedDate"`
+	BetCount            int                `json:"betCount"`
+	Commission          float32            `json:"commission"`
+	PriceMatched        float32            `json:"priceMatched"`
+	PriceReduced        bool               `json:"priceReduced"`
+	SizeSettled         float32            `json:"sizeSettled"`
+	Profit              float32            `json:"profit"`
+	SizeCancelled       float32            `json:"sizeCancelled"`
+	CustomerOrderRef    string             `json:"customerOrderRef"`
+	CustomerStrategyRef string             `json:"customerStrategyRef"`
+}
+
+// ClearedOrderSummaryReport A container representing search results
+type ClearedOrderSummaryReport struct {
+	ClearedOrders []ClearedOrderSummary `json:"clearedOrders"`
+	MoreAvailable bool                  `json:"moreAvailable"`
+}
+
+// ClearedOrdersIterator walks through every cleared order matching some
+// parameters, requesting the next page whenever more orders are available.
+type ClearedOrdersIterator struct {
+	pager
+	ctx       context.Context
+	s         *Session
+	betStatus BetStatusVal
+	params    ClearedOrdersParams
+	orders    []ClearedOrderSummary
+	current   ClearedOrderSummary
+}
+
+// Next advances to the next order, returning false when there are no more
+// orders or a request failed.
+func (it *ClearedOrdersIterator) Next() bool {
+	if !it.next(it.fetch) {
+		return false
+	}
+	it.current, it.orders = it.orders[0], it.orders[1:]
+	return true
+}
+
+func (it *ClearedOrdersIterator) fetch(from int) (int, bool, error) {
+	it.params.FromRecord = from
+	report, err := it.s.ListClearedOrdersContext(it.ctx, it.betStatus, &it.params)
+	it.orders = report.ClearedOrders
+	return len(report.ClearedOrders), report.MoreAvailable, err
+}
+
+// Order returns the order the iterator is positioned on.
+func (it *ClearedOrdersIterator) Order() ClearedOrderSummary {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *ClearedOrdersIterator) Err() error {
+	return it.err
+}
+
+type placeOrdersParams struct {
+	MarketId            string             `json:"marketId"`
+	Instructions        []PlaceInstruction `json:"instructions"`
+	CustomerRef         string             `json:"customerRef,omitempty"`
+	MarketVersion       *MarketVersion     `json:"marketVersion,omitempty"`
+	CustomerStrategyRef string             `json:"customerStrategyRef,omitempty"`
+}
+
+type cancelOrdersParams struct {
+	MarketId     string              `json:"marketId,omitempty"`
+	Instructions []CancelInstruction `json:"instructions,omitempty"`
+	CustomerRef  string              `json:"customerRef,omitempty"`
+}
+
+type replaceOrdersParams struct {
+	MarketId      string               `json:"marketId"`
+	Instructions  []ReplaceInstruction `json:"instructions"`
+	CustomerRef   string               `json:"customerRef,omitempty"`
+	MarketVersion *MarketVersion       `json:"marketVersion,omitempty"`
+}
+
+type updateOrdersParams struct {
+	MarketId     string              `json:"marketId"`
+	Instructions []UpdateInstruction `json:"instructions"`
+	CustomerRef  string              `json:"customerRef,omitempty"`
+}
+
 // Returns a list of Competitions (i.e., World Cup 2013) associated with the
 // markets selected by the MarketFilter.
 func (s *Session) ListCompetitions(filter *MarketFilter) ([]CompetitionResult, error) {
+	return s.ListCompetitionsContext(context.Background(), filter)
+}
+
+// ListCompetitionsContext is like ListCompetitions, but the request is bound to ctx.
+func (s *Session) ListCompetitionsContext(ctx context.Context, filter *MarketFilter) ([]CompetitionResult, error) {
 	var results []CompetitionResult
 	params := new(Params)
 	params.MarketFilter = filter
-	err := doBettingRequest(s, "listCompetitions", params, &results)
+	err := doBettingRequest(ctx, s, "listCompetitions", params, &results)
 	return results, err
 }
 
 // Returns a list of Countries associated with the markets selected by the
 // MarketFilter.
 func (s *Session) ListCountries(filter *MarketFilter) ([]CountryCodeResult, error) {
+	return s.ListCountriesContext(context.Background(), filter)
+}
+
+// ListCountriesContext is like ListCountries, but the request is bound to ctx.
+func (s *Session) ListCountriesContext(ctx context.Context, filter *MarketFilter) ([]CountryCodeResult, error) {
 	var results []CountryCodeResult
 	params := new(Params)
 	params.MarketFilter = filter
-	err := doBettingRequest(s, "listCountries", params, &results)
+	err := doBettingRequest(ctx, s, "listCountries", params, &results)
 	return results, err
 }
 
 // Returns a list of Events (i.e, Reading vs. Man United) associated with the
 // markets selected by the MarketFilter.
 func (s *Session) ListEvents(filter *MarketFilter) ([]EventResult, error) {
+	return s.ListEventsContext(context.Background(), filter)
+}
+
+// ListEventsContext is like ListEvents, but the request is bound to ctx.
+func (s *Session) ListEventsContext(ctx context.Context, filter *MarketFilter) ([]EventResult, error) {
 	var results []EventResult
 	params := new(Params)
 	params.MarketFilter = filter
-	err := doBettingRequest(s, "listEvents", params, &results)
+	err := doBettingRequest(ctx, s, "listEvents", params, &results)
 	return results, err
 }
 
 // Returns a list of Event Types (i.e. Sports) associated with the markets
 // selected by the MarketFilter.
 func (s *Session) ListEventTypes(filter *MarketFilter) ([]EventTypeResult, error) {
+	return s.ListEventTypesContext(context.Background(), filter)
+}
+
+// ListEventTypesContext is like ListEventTypes, but the request is bound to ctx.
+func (s *Session) ListEventTypesContext(ctx context.Context, filter *MarketFilter) ([]EventTypeResult, error) {
 	var results []EventTypeResult
 	params := new(Params)
 	params.MarketFilter = filter
-	err := doBettingRequest(s, "listEventTypes", params, &results)
+	err := doBettingRequest(ctx, s, "listEventTypes", params, &results)
 	return results, err
 }
 
@@ -399,42 +1131,361 @@ func (s *Session) ListEventTypes(filter *MarketFilter) ([]EventTypeResult, error
 // the status of the market, the status of selections, the traded volume, and
 // the status of any orders you have placed in the market.
 func (s *Session) ListMarketBook(marketIds []string, projectionsParam *ProjectionParams) ([]MarketBook, error) {
-	var results []MarketBook
+	return s.ListMarketBookContext(context.Background(), marketIds, projectionsParam)
+}
+
+// ListMarketBookContext is like ListMarketBook, but the request is bound to ctx.
+func (s *Session) ListMarketBookContext(ctx context.Context, marketIds []string, projectionsParam *ProjectionParams) ([]MarketBook, error) {
 	params := new(Params)
 	params.MarketIds = marketIds
 	params.SetProjections(projectionsParam)
-	err := doBettingRequest(s, "listMarketBook", params, &results)
+	return listMarketBookBatches(ctx, s, params)
+}
+
+// ListMarketBookWithOptions is like ListMarketBook, but accepts every
+// optional parameter of listMarketBook, i.e. to get the position by strategy
+// or only the orders matched since a given time. Options may be nil.
+func (s *Session) ListMarketBookWithOptions(marketIds []string, options *MarketBookOptions) ([]MarketBook, error) {
+	return s.ListMarketBookWithOptionsContext(context.Background(), marketIds, options)
+}
+
+// ListMarketBookWithOptionsContext is like ListMarketBookWithOptions, but the request is bound to ctx.
+func (s *Session) ListMarketBookWithOptionsContext(ctx context.Context, marketIds []string, options *MarketBookOptions) ([]MarketBook, error) {
+	params := new(Params)
+	params.MarketIds = marketIds
+	if options != nil {
+		params.SetMarketBookOptions(options)
+	}
+	return listMarketBookBatches(ctx, s, params)
+}
+
+// ListRunnerBook Returns a list of dynamic data about a market and a specified
+// runner. The returned MarketBook contains the requested runner only.
+// Options may be nil.
+func (s *Session) ListRunnerBook(marketId string, selectionId uint32, handicap float32, options *MarketBookOptions) ([]MarketBook, error) {
+	return s.ListRunnerBookContext(context.Background(), marketId, selectionId, handicap, options)
+}
+
+// ListRunnerBookContext is like ListRunnerBook, but the request is bound to ctx.
+func (s *Session) ListRunnerBookContext(ctx context.Context, marketId string, selectionId uint32, handicap float32, options *MarketBookOptions) ([]MarketBook, error) {
+	var results []MarketBook
+	params := new(Params)
+	params.MarketId = marketId
+	params.SelectionId = selectionId
+	params.Handicap = handicap
+	if options != nil {
+		params.SetMarketBookOptions(options)
+	}
+	err := doBettingRequest(ctx, s, "listRunnerBook", params, &results)
+	return results, err
+}
+
+// ListMarketProfitAndLoss Retrieve profit and loss for a given list of OPEN
+// markets. The values are calculated using matched bets and optionally
+// settled bets and BSP bets, optionally net of commission.
+func (s *Session) ListMarketProfitAndLoss(marketIds []string, includeSettledBets, includeBspBets, netOfCommission bool) ([]MarketProfitAndLoss, error) {
+	return s.ListMarketProfitAndLossContext(context.Background(), marketIds, includeSettledBets, includeBspBets, netOfCommission)
+}
+
+// ListMarketProfitAndLossContext is like ListMarketProfitAndLoss, but the request is bound to ctx.
+func (s *Session) ListMarketProfitAndLossContext(ctx context.Context, marketIds []string, includeSettledBets, includeBspBets, netOfCommission bool) ([]MarketProfitAndLoss, error) {
+	var results []MarketProfitAndLoss
+	params := new(Params)
+	params.MarketIds = marketIds
+	params.IncludeSettledBets = includeSettledBets
+	params.IncludeBspBets = includeBspBets
+	params.NetOfCommission = netOfCommission
+	err := doBettingRequest(ctx, s, "listMarketProfitAndLoss", params, &results)
 	return results, err
 }
 
 // ListMarketCatalogue Returns a list of information about markets that does not change (or
 // changes very rarely). You use listMarketCatalogue to retrieve the name
 // of the market, the names of selections and other information about markets.
-// Market Data Request Limits apply to requests made to listMarketCatalogue.
+// Market Data Request Limits apply to requests made to listMarketCatalogue:
+// heavier requests are split by filter.MarketIds, or fail with
+// TOO_MUCH_DATA without being sent when the filter has no market ids.
 func (s *Session) ListMarketCatalogue(filter *MarketFilter, maxResults int, projectionsParam *ProjectionParams) ([]MarketCatalogue, error) {
+	return s.ListMarketCatalogueContext(context.Background(), filter, maxResults, projectionsParam)
+}
+
+// ListMarketCatalogueContext is like ListMarketCatalogue, but the request is bound to ctx.
+func (s *Session) ListMarketCatalogueContext(ctx context.Context, filter *MarketFilter, maxResults int, projectionsParam *ProjectionParams) ([]MarketCatalogue, error) {
 	var results []MarketCatalogue
 	params := new(Params)
 	params.MarketFilter = filter
 	params.MaxResults = maxResults
 	params.SetProjections(projectionsParam)
-	err := doBettingRequest(s, "listMarketCatalogue", params, &results)
-	return results, err
+	perMarket := MarketCatalogueWeight(params.MarketProjection, 1)
+	// Betfair weighs the markets returned, at most maxResults.
+	var marketIds []string
+	if filter != nil {
+		marketIds = filter.MarketIds
+	}
+	markets := maxResults
+	if len(marketIds) > 0 && (markets <= 0 || len(marketIds) < markets) {
+		markets = len(marketIds)
+	}
+	if perMarket*markets <= MaxRequestWeight {
+		err := doBettingRequest(ctx, s, "listMarketCatalogue", params, &results)
+		return results, err
+	}
+	if len(marketIds) == 0 {
+		// Too heavy, but there are no ids to split the request by.
+		return nil, &APIError{
+			Method:    "listMarketCatalogue",
+			ErrorCode: ErrorCodeTooMuchData,
+			Message:   fmt.Sprintf("at most %d markets per request with these projections", MaxRequestWeight/perMarket),
+		}
+	}
+	// Too heavy, request the markets in batches.
+	for _, batchIds := range splitMarketIds(marketIds, perMarket) {
+		var batch []MarketCatalogue
+		batchFilter := *filter
+		batchFilter.MarketIds = batchIds
+		batchParams := *params
+		batchParams.MarketFilter = &batchFilter
+		batchParams.MaxResults = len(batchIds)
+		if err := doBettingRequest(ctx, s, "listMarketCatalogue", &batchParams, &batch); err != nil {
+			return results, err
+		}
+		results = append(results, batch...)
+		if maxResults > 0 && len(results) >= maxResults {
+			return results[:maxResults], nil
+		}
+	}
+	return results, nil
 }
 
 // Returns a list of market types (i.e. MATCH_ODDS, NEXT_GOAL) associated
 // with the markets selected by the MarketFilter. The market types are always
 // the same, regardless of locale.
 func (s *Session) ListMarketTypes(filter *MarketFilter) ([]MarketTypeResult, error) {
+	return s.ListMarketTypesContext(context.Background(), filter)
+}
+
+// ListMarketTypesContext is like ListMarketTypes, but the request is bound to ctx.
+func (s *Session) ListMarketTypesContext(ctx context.Context, filter *MarketFilter) ([]MarketTypeResult, error) {
 	var results []MarketTypeResult
 	params := new(Params)
 	params.MarketFilter = filter
-	err := doBettingRequest(s, "listMarketTypes", params, &results)
+	err := doBettingRequest(ctx, s, "listMarketTypes", params, &results)
+	return results, err
+}
+
+// Returns a list of time ranges in the granularity specified in the request
+// (i.e. 3PM to 4PM, Aug 14th to Aug 15th) associated with the markets
+// selected by the MarketFilter.
+func (s *Session) ListTimeRanges(filter *MarketFilter, granularity TimeGranularityVal) ([]TimeRangeResult, error) {
+	return s.ListTimeRangesContext(context.Background(), filter, granularity)
+}
+
+// ListTimeRangesContext is like ListTimeRanges, but the request is bound to ctx.
+func (s *Session) ListTimeRangesContext(ctx context.Context, filter *MarketFilter, granularity TimeGranularityVal) ([]TimeRangeResult, error) {
+	var results []TimeRangeResult
+	params := new(Params)
+	params.MarketFilter = filter
+	params.Granularity = granularity
+	err := doBettingRequest(ctx, s, "listTimeRanges", params, &results)
+	return results, err
+}
+
+// Returns a list of Venues (i.e. Cheltenham, Ascot) associated with the
+// markets selected by the MarketFilter. Currently, only Horse Racing
+// markets are associated with a Venue.
+func (s *Session) ListVenues(filter *MarketFilter) ([]VenueResult, error) {
+	return s.ListVenuesContext(context.Background(), filter)
+}
+
+// ListVenuesContext is like ListVenues, but the request is bound to ctx.
+func (s *Session) ListVenuesContext(ctx context.Context, filter *MarketFilter) ([]VenueResult, error) {
+	var results []VenueResult
+	params := new(Params)
+	params.MarketFilter = filter
+	err := doBettingRequest(ctx, s, "listVenues", params, &results)
 	return results, err
 }
 
-func doBettingRequest(s *Session, method string, params *Params, v interface{}) error {
+// PlaceOrders Place new orders into market. The customerRef is used to
+// de-duplicate mistaken re-submissions, customerStrategyRef identifies the
+// strategy the orders belong to. A marketVersion greater than zero makes
+// the orders lapse if the market has been updated since that version.
+func (s *Session) PlaceOrders(marketId string, instructions []PlaceInstruction, customerRef, customerStrategyRef string, marketVersion int) (PlaceExecutionReport, error) {
+	return s.PlaceOrdersContext(context.Background(), marketId, instructions, customerRef, customerStrategyRef, marketVersion)
+}
+
+// PlaceOrdersContext is like PlaceOrders, but the request is bound to ctx.
+func (s *Session) PlaceOrdersContext(ctx context.Context, marketId string, instructions []PlaceInstruction, customerRef, customerStrategyRef string, marketVersion int) (PlaceExecutionReport, error) {
+	var report PlaceExecutionReport
+	params := &placeOrdersParams{
+		MarketId:            marketId,
+		Instructions:        instructions,
+		CustomerRef:         customerRef,
+		CustomerStrategyRef: customerStrategyRef,
+	}
+	if marketVersion > 0 {
+		params.MarketVersion = &MarketVersion{Version: marketVersion}
+	}
+	if customerRef == "" {
+		// Without customerRef, a retry could place the orders twice.
+		ctx = withoutRetry(ctx)
+	}
+	err := doBettingRequest(ctx, s, "placeOrders", params, &report)
+	return report, err
+}
+
+// CancelOrders Cancel all bets OR cancel all bets on a market OR fully or
+// partially cancel particular orders on a market. Leave marketId and
+// instructions empty to cancel all bets on all markets.
+func (s *Session) CancelOrders(marketId string, instructions []CancelInstruction, customerRef string) (CancelExecutionReport, error) {
+	return s.CancelOrdersContext(context.Background(), marketId, instructions, customerRef)
+}
+
+// CancelOrdersContext is like CancelOrders, but the request is bound to ctx.
+func (s *Session) CancelOrdersContext(ctx context.Context, marketId string, instructions []CancelInstruction, customerRef string) (CancelExecutionReport, error) {
+	var report CancelExecutionReport
+	params := &cancelOrdersParams{
+		MarketId:     marketId,
+		Instructions: instructions,
+		CustomerRef:  customerRef,
+	}
+	err := doBettingRequest(ctx, s, "cancelOrders", params, &report)
+	return report, err
+}
+
+// ReplaceOrders This operation is logically a bulk cancel followed by a bulk
+// place. The cancel is completed first then the new orders are placed.
+// A marketVersion greater than zero is handled as in PlaceOrders.
+func (s *Session) ReplaceOrders(marketId string, instructions []ReplaceInstruction, customerRef string, marketVersion int) (ReplaceExecutionReport, error) {
+	return s.ReplaceOrdersContext(context.Background(), marketId, instructions, customerRef, marketVersion)
+}
+
+// ReplaceOrdersContext is like ReplaceOrders, but the request is bound to ctx.
+func (s *Session) ReplaceOrdersContext(ctx context.Context, marketId string, instructions []ReplaceInstruction, customerRef string, marketVersion int) (ReplaceExecutionReport, error) {
+	var report ReplaceExecutionReport
+	params := &replaceOrdersParams{
+		MarketId:     marketId,
+		Instructions: instructions,
+		CustomerRef:  customerRef,
+	}
+	if marketVersion > 0 {
+		params.MarketVersion = &MarketVersion{Version: marketVersion}
+	}
+	err := doBettingRequest(ctx, s, "replaceOrders", params, &report)
+	return report, err
+}
+
+// UpdateOrders Update non-exposure changing fields, i.e. the persistence
+// type of LIMIT orders.
+func (s *Session) UpdateOrders(marketId string, instructions []UpdateInstruction, customerRef string) (UpdateExecutionReport, error) {
+	return s.UpdateOrdersContext(context.Background(), marketId, instructions, customerRef)
+}
+
+// UpdateOrdersContext is like UpdateOrders, but the request is bound to ctx.
+func (s *Session) UpdateOrdersContext(ctx context.Context, marketId string, instructions []UpdateInstruction, customerRef string) (UpdateExecutionReport, error) {
+	var report UpdateExecutionReport
+	params := &updateOrdersParams{
+		MarketId:     marketId,
+		Instructions: instructions,
+		CustomerRef:  customerRef,
+	}
+	err := doBettingRequest(ctx, s, "updateOrders", params, &report)
+	return report, err
+}
+
+// ListCurrentOrders Returns a list of your current orders. Pass nil params to
+// get the first page of all current orders.
+func (s *Session) ListCurrentOrders(params *CurrentOrdersParams) (CurrentOrderSummaryReport, error) {
+	return s.ListCurrentOrdersContext(context.Background(), params)
+}
+
+// ListCurrentOrdersContext is like ListCurrentOrders, but the request is bound to ctx.
+func (s *Session) ListCurrentOrdersContext(ctx context.Context, params *CurrentOrdersParams) (CurrentOrderSummaryReport, error) {
+	var report CurrentOrderSummaryReport
+	if params == nil {
+		params = new(CurrentOrdersParams)
+	}
+	err := doBettingRequest(ctx, s, "listCurrentOrders", params, &report)
+	return report, err
+}
+
+// IterateCurrentOrders Returns an iterator over all your current orders
+// matching params, following moreAvailable from params.FromRecord onwards.
+func (s *Session) IterateCurrentOrders(params *CurrentOrdersParams) *CurrentOrdersIterator {
+	return s.IterateCurrentOrdersContext(context.Background(), params)
+}
+
+// IterateCurrentOrdersContext is like IterateCurrentOrders, but every request is bound to ctx.
+func (s *Session) IterateCurrentOrdersContext(ctx context.Context, params *CurrentOrdersParams) *CurrentOrdersIterator {
+	it := &CurrentOrdersIterator{ctx: ctx, s: s}
+	if params != nil {
+		it.params = *params
+		it.from = params.FromRecord
+	}
+	return it
+}
+
+// ListClearedOrders Returns a list of settled bets based on the bet status,
+// ordered by settled date. Pass nil params to get the first page of all
+// bets with the given status.
+func (s *Session) ListClearedOrders(betStatus BetStatusVal, params *ClearedOrdersParams) (ClearedOrderSummaryReport, error) {
+	return s.ListClearedOrdersContext(context.Background(), betStatus, params)
+}
 
-	params.Locale = s.config.Locale
+// ListClearedOrdersContext is like ListClearedOrders, but the request is bound to ctx.
+func (s *Session) ListClearedOrdersContext(ctx context.Context, betStatus BetStatusVal, params *ClearedOrdersParams) (ClearedOrderSummaryReport, error) {
+	var report ClearedOrderSummaryReport
+	if params == nil {
+		params = new(ClearedOrdersParams)
+	}
+	request := &clearedOrdersParams{
+		BetStatus:           betStatus,
+		Locale:              s.config.Locale,
+		ClearedOrdersParams: params,
+	}
+	err := doBettingRequest(ctx, s, "listClearedOrders", request, &report)
+	return report, err
+}
+
+// IterateClearedOrders Returns an iterator over all your cleared orders
+// matching params, following moreAvailable from params.FromRecord onwards.
+func (s *Session) IterateClearedOrders(betStatus BetStatusVal, params *ClearedOrdersParams) *ClearedOrdersIterator {
+	return s.IterateClearedOrdersContext(context.Background(), betStatus, params)
+}
+
+// IterateClearedOrdersContext is like IterateClearedOrders, but every request is bound to ctx.
+func (s *Session) IterateClearedOrdersContext(ctx context.Context, betStatus BetStatusVal, params *ClearedOrdersParams) *ClearedOrdersIterator {
+	it := &ClearedOrdersIterator{ctx: ctx, s: s, betStatus: betStatus}
+	if params != nil {
+		it.params = *params
+		it.from = params.FromRecord
+	}
+	return it
+}
+
+// Requests the market books in batches not exceeding MaxRequestWeight, the
+// results are merged in the order of the batches.
+func listMarketBookBatches(ctx context.Context, s *Session, params *Params) ([]MarketBook, error) {
+	var results []MarketBook
+	perMarket := MarketBookWeight(params.PriceProjection, 1)
+	for _, marketIds := range splitMarketIds(params.MarketIds, perMarket) {
+		var batch []MarketBook
+		batchParams := *params
+		batchParams.MarketIds = marketIds
+		if err := doBettingRequest(ctx, s, "listMarketBook", &batchParams, &batch); err != nil {
+			return results, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+func doBettingRequest(ctx context.Context, s *Session, method string, params interface{}, v interface{}) error {
+
+	// Order operations take their own parameters, which carry no locale.
+	if p, ok := params.(*Params); ok {
+		p.Locale = s.config.Locale
+	}
 
 	bytes, err := json.Marshal(params)
 	if err != nil {
@@ -442,7 +1493,7 @@ func doBettingRequest(s *Session, method string, params *Params, v interface{})
 	}
 	body := strings.NewReader(string(bytes))
 
-	data, err := doRequest(s, "betting", method+"/", body)
+	data, err := doRequest(ctx, s, "betting", method+"/", body)
 	if err != nil {
 		return err
 	}
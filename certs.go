@@ -0,0 +1,112 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// CertificateProvider supplies the client certificate of the non-interactive
+// login, i.e. from a secret store. It is called on every TLS handshake, so
+// that the certificate may be rotated.
+type CertificateProvider interface {
+	ClientCertificate() (*tls.Certificate, error)
+}
+
+// CertificateProviderFunc adapts a function to a CertificateProvider.
+type CertificateProviderFunc func() (*tls.Certificate, error)
+
+// ClientCertificate calls f.
+func (f CertificateProviderFunc) ClientCertificate() (*tls.Certificate, error) {
+	return f()
+}
+
+// Loads the client certificate from, in order of precedence, a PKCS#12
+// bundle, PEM blocks or files. Nothing is loaded when a provider is set.
+func loadCertificates(c *Config) ([]tls.Certificate, error) {
+	if c.CertificateProvider != nil {
+		return nil, nil
+	}
+	if len(c.PKCS12) > 0 {
+		cert, err := parsePKCS12(c.PKCS12, c.PKCS12Password)
+		if err != nil {
+			return nil, errors.New("Config.PKCS12 is invalid: " + err.Error())
+		}
+		return []tls.Certificate{cert}, nil
+	}
+	if len(c.CertPEM) > 0 || len(c.KeyPEM) > 0 {
+		cert, err := tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+		if err != nil {
+			return nil, errors.New("Config.CertPEM or Config.KeyPEM is invalid: " + err.Error())
+		}
+		return []tls.Certificate{cert}, nil
+	}
+	if _, err := os.Stat(c.CertFile); os.IsNotExist(err) {
+		return nil, errors.New("Config.CertFile does not exist.")
+	}
+	if _, err := os.Stat(c.KeyFile); os.IsNotExist(err) {
+		return nil, errors.New("Config.KeyFile does not exist.")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return []tls.Certificate{cert}, nil
+}
+
+// Decodes a .p12 bundle holding the client certificate, its chain and its
+// private key. Bundles encrypted with PBES2 and AES, the default of OpenSSL
+// 3, are supported.
+func parsePKCS12(data []byte, password string) (tls.Certificate, error) {
+	key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return tls.Certificate{}, errors.New("Unsupported private key")
+	}
+	// The client certificate is the one of the key, which does not always
+	// come first in the bundle.
+	certs := append([]*x509.Certificate{leaf}, chain...)
+	for i, cert := range certs {
+		public, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+		if !ok || !public.Equal(signer.Public()) {
+			continue
+		}
+		certs[0], certs[i] = certs[i], certs[0]
+		result := tls.Certificate{PrivateKey: key, Leaf: certs[0]}
+		for _, cert := range certs {
+			result.Certificate = append(result.Certificate, cert.Raw)
+		}
+		return result, nil
+	}
+	return tls.Certificate{}, errors.New("No certificate matches the private key")
+}
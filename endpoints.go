@@ -0,0 +1,153 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Keys of the endpoints used by the session.
+const (
+	EndpointCertLogin  = "certLogin"
+	EndpointLogin      = "login"
+	EndpointAuth       = "auth"
+	EndpointBetting    = "betting"
+	EndpointAccount    = "account"
+	EndpointHeartbeat  = "heartbeat"
+	EndpointRaceStatus = "raceStatus"
+)
+
+// Endpoints maps endpoint keys to the base URL of the service and the HTTP
+// method used to call it.
+type Endpoints map[string]RequestSpecification
+
+var ukEndpoints = Endpoints{
+	EndpointCertLogin:  {"https://identitysso-api.betfair.com/api/certlogin", "POST"},
+	EndpointLogin:      {"https://identitysso.betfair.com/api/login", "POST"},
+	EndpointAuth:       {"https://identitysso.betfair.com/api/", "POST"},
+	EndpointBetting:    {"https://api.betfair.com/exchange/betting/rest/v1.0/", "POST"},
+	EndpointAccount:    {"https://api.betfair.com/exchange/account/rest/v1.0/", "POST"},
+	EndpointHeartbeat:  {"https://api.betfair.com/exchange/heartbeat/json-rpc/v1", "POST"},
+	EndpointRaceStatus: {"https://api.betfair.com/exchange/scores/rest/v1.0/", "POST"},
+}
+
+var auEndpoints = Endpoints{
+	EndpointCertLogin:  {"https://identitysso-api.betfair.com/api/certlogin", "POST"},
+	EndpointLogin:      {"https://identitysso.betfair.com/api/login", "POST"},
+	EndpointAuth:       {"https://identitysso.betfair.com/api/", "POST"},
+	EndpointBetting:    {"https://api-au.betfair.com/exchange/betting/rest/v1.0/", "POST"},
+	EndpointAccount:    {"https://api-au.betfair.com/exchange/account/rest/v1.0/", "POST"},
+	EndpointHeartbeat:  {"https://api-au.betfair.com/exchange/heartbeat/json-rpc/v1", "POST"},
+	EndpointRaceStatus: {"https://api-au.betfair.com/exchange/scores/rest/v1.0/", "POST"},
+}
+
+var itEndpoints = Endpoints{
+	EndpointCertLogin:  {"https://identitysso-cert.betfair.it/api/certlogin", "POST"},
+	EndpointLogin:      {"https://identitysso.betfair.it/api/login", "POST"},
+	EndpointAuth:       {"https://identitysso.betfair.it/api/", "POST"},
+	EndpointBetting:    {"https://api.betfair.it/exchange/betting/rest/v1.0/", "POST"},
+	EndpointAccount:    {"https://api.betfair.it/exchange/account/rest/v1.0/", "POST"},
+	EndpointHeartbeat:  {"https://api.betfair.it/exchange/heartbeat/json-rpc/v1", "POST"},
+	EndpointRaceStatus: {"https://api.betfair.it/exchange/scores/rest/v1.0/", "POST"},
+}
+
+var esEndpoints = Endpoints{
+	EndpointCertLogin:  {"https://identitysso-cert.betfair.es/api/certlogin", "POST"},
+	EndpointLogin:      {"https://identitysso.betfair.es/api/login", "POST"},
+	EndpointAuth:       {"https://identitysso.betfair.es/api/", "POST"},
+	EndpointBetting:    {"https://api.betfair.es/exchange/betting/rest/v1.0/", "POST"},
+	EndpointAccount:    {"https://api.betfair.es/exchange/account/rest/v1.0/", "POST"},
+	EndpointHeartbeat:  {"https://api.betfair.es/exchange/heartbeat/json-rpc/v1", "POST"},
+	EndpointRaceStatus: {"https://api.betfair.es/exchange/scores/rest/v1.0/", "POST"},
+}
+
+// NewEndpoints builds the endpoints of a server exposing every service under
+// the same base URL, with the paths used by betfair, i.e. a local test
+// server.
+func NewEndpoints(baseURL string) Endpoints {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return Endpoints{
+		EndpointCertLogin:  {baseURL + "/api/certlogin", "POST"},
+		EndpointLogin:      {baseURL + "/api/login", "POST"},
+		EndpointAuth:       {baseURL + "/api/", "POST"},
+		EndpointBetting:    {baseURL + "/exchange/betting/rest/v1.0/", "POST"},
+		EndpointAccount:    {baseURL + "/exchange/account/rest/v1.0/", "POST"},
+		EndpointHeartbeat:  {baseURL + "/exchange/heartbeat/json-rpc/v1", "POST"},
+		EndpointRaceStatus: {baseURL + "/exchange/scores/rest/v1.0/", "POST"},
+	}
+}
+
+// EndpointRegistry maps exchanges (i.e. "UK") to their endpoints. It is safe
+// for concurrent use.
+type EndpointRegistry struct {
+	mu        sync.RWMutex
+	exchanges map[string]Endpoints
+}
+
+// NewEndpointRegistry returns a registry knowing the UK, AU, IT and ES
+// exchanges.
+func NewEndpointRegistry() *EndpointRegistry {
+	return &EndpointRegistry{
+		exchanges: map[string]Endpoints{
+			"UK": ukEndpoints,
+			"AU": auEndpoints,
+			"IT": itEndpoints,
+			"ES": esEndpoints,
+		},
+	}
+}
+
+// DefaultEndpoints is the registry used by sessions whose Config does not
+// set Endpoints.
+var DefaultEndpoints = NewEndpointRegistry()
+
+// Register adds or replaces the endpoints of an exchange.
+func (r *EndpointRegistry) Register(exchange string, endpoints Endpoints) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges[strings.ToUpper(exchange)] = endpoints.clone()
+}
+
+// Lookup returns a copy of the endpoints of an exchange.
+func (r *EndpointRegistry) Lookup(exchange string) (Endpoints, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	endpoints, exists := r.exchanges[strings.ToUpper(exchange)]
+	if !exists {
+		return nil, errors.New("Unknown exchange: " + exchange)
+	}
+	return endpoints.clone(), nil
+}
+
+// Returns a copy, so that the endpoints of the registry and of the sessions
+// are not shared with the caller.
+func (e Endpoints) clone() Endpoints {
+	endpoints := make(Endpoints, len(e))
+	for key, spec := range e {
+		endpoints[key] = spec
+	}
+	return endpoints
+}
@@ -0,0 +1,150 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ErrorCodeVal Enum of error codes returned by the betting, account and
+// identity APIs, including the login failures
+type ErrorCodeVal baseEnumVal
+
+// Constant values for API-NG error codes
+const (
+	ErrorCodeTooMuchData               ErrorCodeVal = "TOO_MUCH_DATA"
+	ErrorCodeInvalidInputData                       = "INVALID_INPUT_DATA"
+	ErrorCodeInvalidSessionInformation              = "INVALID_SESSION_INFORMATION"
+	ErrorCodeNoAppKey                               = "NO_APP_KEY"
+	ErrorCodeNoSession                              = "NO_SESSION"
+	ErrorCodeUnexpectedError                        = "UNEXPECTED_ERROR"
+	ErrorCodeInvalidAppKey                          = "INVALID_APP_KEY"
+	ErrorCodeTooManyRequests                        = "TOO_MANY_REQUESTS"
+	ErrorCodeServiceBusy                            = "SERVICE_BUSY"
+	ErrorCodeTimeoutError                           = "TIMEOUT_ERROR"
+	ErrorCodeRequestSizeExceedsLimit                = "REQUEST_SIZE_EXCEEDS_LIMIT"
+	ErrorCodeAccessDenied                           = "ACCESS_DENIED"
+	ErrorCodeSubscriptionExpired                    = "SUBSCRIPTION_EXPIRED"
+	ErrorCodeNoResponse                             = "NO_RESPONSE"
+	ErrorCodeInvalidClientRef                       = "INVALID_CLIENT_REF"
+	ErrorCodeInvalidToken                           = "INVALID_TOKEN"
+	ErrorCodeInputValidationError                   = "INPUT_VALIDATION_ERROR"
+	ErrorCodeInternalError                          = "INTERNAL_ERROR"
+)
+
+// Constant values for login error codes
+const (
+	ErrorCodeInvalidUsernameOrPassword            ErrorCodeVal = "INVALID_USERNAME_OR_PASSWORD"
+	ErrorCodeAccountNowLocked                                  = "ACCOUNT_NOW_LOCKED"
+	ErrorCodeAccountAlreadyLocked                              = "ACCOUNT_ALREADY_LOCKED"
+	ErrorCodeAccountPendingPasswordChange                      = "ACCOUNT_PENDING_PASSWORD_CHANGE"
+	ErrorCodeChangePasswordRequired                            = "CHANGE_PASSWORD_REQUIRED"
+	ErrorCodePendingAuth                                       = "PENDING_AUTH"
+	ErrorCodeCertAuthRequired                                  = "CERT_AUTH_REQUIRED"
+	ErrorCodeSuspended                                         = "SUSPENDED"
+	ErrorCodeClosed                                            = "CLOSED"
+	ErrorCodeSelfExcluded                                      = "SELF_EXCLUDED"
+	ErrorCodeKYCSuspend                                        = "KYC_SUSPEND"
+	ErrorCodeSecurityRestrictedLocation                        = "SECURITY_RESTRICTED_LOCATION"
+	ErrorCodeBettingRestrictedLocation                         = "BETTING_RESTRICTED_LOCATION"
+	ErrorCodeTemporaryBanTooManyRequests                       = "TEMPORARY_BAN_TOO_MANY_REQUESTS"
+	ErrorCodeEmailLoginNotAllowed                              = "EMAIL_LOGIN_NOT_ALLOWED"
+	ErrorCodeMultipleUsersWithSameCredential                   = "MULTIPLE_USERS_WITH_SAME_CREDENTIAL"
+	ErrorCodePersonalMessageRequired                           = "PERSONAL_MESSAGE_REQUIRED"
+	ErrorCodeInternationalTermsAcceptanceRequired              = "INTERNATIONAL_TERMS_ACCEPTANCE_REQUIRED"
+	ErrorCodeSpanishTermsAcceptanceRequired                    = "SPANISH_TERMS_ACCEPTANCE_REQUIRED"
+	ErrorCodeItalianContractAcceptanceRequired                 = "ITALIAN_CONTRACT_ACCEPTANCE_REQUIRED"
+	ErrorCodeDanishAuthorizationRequired                       = "DANISH_AUTHORIZATION_REQUIRED"
+	ErrorCodeTradingMaster                                     = "TRADING_MASTER"
+	ErrorCodeTradingMasterSuspended                            = "TRADING_MASTER_SUSPENDED"
+	ErrorCodeAgentClientMaster                                 = "AGENT_CLIENT_MASTER"
+	ErrorCodeAgentClientMasterSuspended                        = "AGENT_CLIENT_MASTER_SUSPENDED"
+)
+
+// APIError is returned when betfair answers a request with an error. Use
+// errors.As to get it and inspect the ErrorCode, i.e. to login again when
+// the session expired.
+type APIError struct {
+	Method      string
+	StatusCode  int
+	ErrorCode   ErrorCodeVal
+	Message     string
+	RequestUUID string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("betfair: %s: %s", e.Method, e.ErrorCode)
+	if e.ErrorCode == "" {
+		msg = fmt.Sprintf("betfair: %s: %s", e.Method, http.StatusText(e.StatusCode))
+	}
+	if e.Message != "" {
+		msg += " (" + e.Message + ")"
+	}
+	return msg
+}
+
+type apingException struct {
+	ErrorCode    ErrorCodeVal `json:"errorCode"`
+	ErrorDetails string       `json:"errorDetails"`
+	RequestUUID  string       `json:"requestUUID"`
+}
+
+// Betting errors come as APINGException, account errors as
+// AccountAPINGException, both wrapped in a fault whose detail also names
+// the exception.
+type apingFault struct {
+	FaultCode   string `json:"faultcode"`
+	FaultString string `json:"faultstring"`
+	Detail      struct {
+		ExceptionName         string          `json:"exceptionname"`
+		APINGException        *apingException `json:"APINGException"`
+		AccountAPINGException *apingException `json:"AccountAPINGException"`
+	} `json:"detail"`
+}
+
+// Builds an APIError from the body of a non-200 response. The body is not
+// always JSON, i.e. on gateway errors, in which case only the HTTP status
+// is reported.
+func newAPIError(method string, statusCode int, body []byte) *APIError {
+	e := &APIError{Method: method, StatusCode: statusCode}
+	var fault apingFault
+	if err := json.Unmarshal(body, &fault); err != nil {
+		return e
+	}
+	e.Message = fault.FaultString
+	exception := fault.Detail.APINGException
+	if exception == nil {
+		exception = fault.Detail.AccountAPINGException
+	}
+	if exception != nil {
+		e.ErrorCode = exception.ErrorCode
+		e.RequestUUID = exception.RequestUUID
+		if exception.ErrorDetails != "" {
+			e.Message = exception.ErrorDetails
+		}
+	}
+	return e
+}
@@ -1,3 +1,6 @@
+//go:build ignore
+// +build ignore
+
 package main
 
 import (
@@ -37,7 +40,7 @@ func main() {
 	checkErr(loginErr)
 	defer s.Logout()
 
-	s.Live = true
+	s.SetLive(true)
 
 	filter := new(betfair.MarketFilter)
 	filter.EventTypeIds = []string{"1"}
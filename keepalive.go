@@ -0,0 +1,120 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type noReloginKey struct{}
+
+// Marks a request as not triggering a new login, i.e. the requests made
+// while logging in.
+func withoutRelogin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noReloginKey{}, true)
+}
+
+// Tells whether err means that the session token expired or is invalid.
+func isSessionError(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.ErrorCode == ErrorCodeInvalidSessionInformation || apiErr.ErrorCode == ErrorCodeNoSession
+}
+
+// Tells whether a request failed because of the session and should be made
+// again after logging in.
+func (s *Session) shouldRelogin(ctx context.Context, key string, err error) bool {
+	if !s.config.AutoRelogin || ctx.Value(noReloginKey{}) != nil {
+		return false
+	}
+	if key != EndpointBetting && key != EndpointAccount {
+		return false
+	}
+	return isSessionError(err)
+}
+
+// Logs in again, unless another request already did since staleToken was
+// found invalid. Concurrent callers wait for a single login.
+func (s *Session) relogin(ctx context.Context, staleToken string) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	if s.getToken() != staleToken {
+		return nil
+	}
+	return s.login(ctx)
+}
+
+// Starts the keep-alive goroutine, if enabled and not running yet.
+func (s *Session) startKeepAlive() {
+	interval := s.config.KeepAliveInterval
+	if interval <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopKeepAlive != nil {
+		return
+	}
+	stop := make(chan struct{})
+	s.stopKeepAlive = stop
+	go s.keepAliveLoop(interval, stop)
+}
+
+func (s *Session) keepAliveLoop(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		token := s.getToken()
+		err := s.KeepAliveContext(ctx)
+		if err != nil && s.config.AutoRelogin && isSessionError(err) {
+			err = s.relogin(ctx, token)
+		}
+		cancel()
+		if err != nil && s.config.OnKeepAliveError != nil {
+			s.config.OnKeepAliveError(err)
+		}
+	}
+}
+
+// Close stops the keep-alive goroutine, without logging out. It is safe to
+// call Close several times.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopKeepAlive != nil {
+		close(s.stopKeepAlive)
+		s.stopKeepAlive = nil
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MaxRequestWeight is the maximum data weight of a single listMarketBook or
+// listMarketCatalogue request. Heavier requests fail with TOO_MUCH_DATA.
+const MaxRequestWeight = 200
+
+var catalogueWeights = map[MarketProjVal]int{
+	MarketProjectionMarketDescription: 1,
+	MarketProjectionRunnerMetadata:    1,
+}
+
+var bookWeights = map[PriceDataVal]int{
+	PriceDataSPAvailable:  3,
+	PriceDataSPTraded:     7,
+	PriceDataEXBestOffers: 5,
+	PriceDataEXAllOffers:  17,
+	PriceDataEXTraded:     17,
+}
+
+// MarketCatalogueWeight returns the data weight of a listMarketCatalogue
+// request with the given projections returning the given number of markets.
+func MarketCatalogueWeight(projections []MarketProjVal, markets int) int {
+	weight := 0
+	for _, projection := range projections {
+		weight += catalogueWeights[projection]
+	}
+	return weight * markets
+}
+
+// MarketBookWeight returns the data weight of a listMarketBook request with
+// the given price projection on the given number of markets.
+func MarketBookWeight(projection *PriceProjection, markets int) int {
+	if projection == nil || len(projection.PriceData) == 0 {
+		return 2 * markets
+	}
+	data := make(map[PriceDataVal]bool)
+	for _, priceData := range projection.PriceData {
+		data[priceData] = true
+	}
+	weight := 0
+	for priceData := range data {
+		weight += bookWeights[priceData]
+	}
+	// Best offers weigh proportionally to the depth, 3 by default.
+	overrides := projection.ExBestOffersOverrides
+	if data[PriceDataEXBestOffers] && overrides != nil && overrides.BestPricesDepth > 3 {
+		weight -= bookWeights[PriceDataEXBestOffers]
+		weight += (bookWeights[PriceDataEXBestOffers]*overrides.BestPricesDepth + 2) / 3
+	}
+	// Some combinations are cheaper than the sum of their parts.
+	if data[PriceDataEXTraded] && (data[PriceDataEXAllOffers] || data[PriceDataEXBestOffers]) {
+		weight -= 2
+	}
+	return weight * markets
+}
+
+// Splits market ids into batches whose weight, at perMarket points each,
+// does not exceed MaxRequestWeight.
+func splitMarketIds(marketIds []string, perMarket int) [][]string {
+	size := len(marketIds)
+	if perMarket > 0 && MaxRequestWeight/perMarket < size {
+		size = MaxRequestWeight / perMarket
+	}
+	if size < 1 {
+		size = 1
+	}
+	var batches [][]string
+	for len(marketIds) > size {
+		batches = append(batches, marketIds[:size])
+		marketIds = marketIds[size:]
+	}
+	return append(batches, marketIds)
+}
+
+// RateLimiter is a token bucket limiting the rate of requests. It may be
+// shared by several sessions and is safe for concurrent use.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows perSecond requests per second on average, with
+// bursts of up to burst requests.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request is allowed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Takes a token if available, otherwise tells how long to wait for one.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	if l.rate <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
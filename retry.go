@@ -0,0 +1,142 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy retries failed requests with exponential backoff and jitter.
+// Only read methods (list* and get*) are retried, plus placeOrders when
+// RetryPlaceOrders is set and the orders carry a customerRef, which betfair
+// uses to drop duplicate submissions.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt.
+	MaxAttempts int
+	// BaseDelay defaults to 100 milliseconds, MaxDelay to 5 seconds.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// RetryPlaceOrders allows placeOrders to be retried, when a
+	// customerRef is given.
+	RetryPlaceOrders bool
+	// Retryable classifies errors, defaults to IsRetryable.
+	Retryable func(err error) bool `json:"-"`
+}
+
+// IsRetryable reports whether err looks transient: network failures such as
+// timeouts and connection resets, HTTP 429 and 5xx statuses, and API-NG
+// errors asking to try again later.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return isNetworkError(err)
+	}
+	switch apiErr.ErrorCode {
+	case ErrorCodeTooManyRequests, ErrorCodeServiceBusy, ErrorCodeTimeoutError:
+		return true
+	case "":
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return false
+}
+
+type noRetryKey struct{}
+
+// Marks a request as not retriable, whatever the policy.
+func withoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// Tells whether the policy allows to retry a method.
+func (p *RetryPolicy) allows(ctx context.Context, method string) bool {
+	if p == nil || p.MaxAttempts < 2 || ctx.Value(noRetryKey{}) != nil {
+		return false
+	}
+	method = strings.TrimSuffix(method, "/")
+	if method == "placeOrders" {
+		return p.RetryPlaceOrders
+	}
+	return strings.HasPrefix(method, "list") || strings.HasPrefix(method, "get")
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// Waits before the given retry, returning early with the context error.
+func (p *RetryPolicy) wait(ctx context.Context, retry int) error {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	delay := max
+	if retry < 32 && base<<uint(retry) > 0 && base<<uint(retry) < max {
+		delay = base << uint(retry)
+	}
+	// Full jitter, so that clients failing together do not retry together.
+	delay = time.Duration(rand.Int63n(int64(delay)) + 1)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Reports whether the request failed before getting an answer because of
+// the network, i.e. connection reset, rather than i.e. a certificate the
+// client does not trust, which would fail again.
+func isNetworkError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	// The client wraps every error in a url.Error, which is a net.Error.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
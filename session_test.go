@@ -0,0 +1,1271 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Routes the requests of a fake betfair, handlers may be replaced.
+type fakeMux struct {
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+}
+
+func (m *fakeMux) HandleFunc(path string, handler http.HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[path] = handler
+}
+
+func (m *fakeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	handler, exists := m.handlers[r.URL.Path]
+	m.mu.Unlock()
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r)
+}
+
+// Starts a fake betfair answering logins and app keys requests. Tests add
+// their own handlers to the returned mux.
+func newFakeServer(t *testing.T) (*httptest.Server, *fakeMux) {
+	mux := &fakeMux{handlers: make(map[string]http.HandlerFunc)}
+	mux.HandleFunc("/api/certlogin", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"loginStatus":"SUCCESS","sessionToken":"token"}`)
+	})
+	mux.HandleFunc("/exchange/account/rest/v1.0/getDeveloperAppKeys/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("X-Application") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `[{"appName":"app","appVersions":[
+			{"version":"1.0-DELAY","applicationKey":"delayed","delayData":true,"active":true},
+			{"version":"1.0","applicationKey":"live","delayData":false,"active":true}]}]`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, mux
+}
+
+// Creates a session logged into a fake betfair.
+func newFakeSession(t *testing.T, server *httptest.Server) *Session {
+	config := &Config{
+		Username:   "username",
+		Password:   "password",
+		Endpoints:  NewEndpoints(server.URL),
+		HTTPClient: server.Client(),
+	}
+	session, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := session.LoginNonInteractive(); err != nil {
+		t.Fatal(err.Error())
+	}
+	return session
+}
+
+func TestNewSessionUnknownExchange(t *testing.T) {
+	config := &Config{Username: "username", Password: "password", Exchange: "XX"}
+	if _, err := NewSession(config); err == nil {
+		t.Error("Expected an error for an unknown exchange")
+	}
+}
+
+func TestEndpointRegistryCopies(t *testing.T) {
+	registry := NewEndpointRegistry()
+	endpoints, err := registry.Lookup("uk")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	endpoints[EndpointBetting] = RequestSpecification{"http://localhost/", "POST"}
+	if ukEndpoints[EndpointBetting].Url == "http://localhost/" {
+		t.Error("Lookup returned the endpoints of the registry")
+	}
+
+	local := NewEndpoints("http://localhost")
+	registry.Register("local", local)
+	local[EndpointBetting] = RequestSpecification{"http://other/", "POST"}
+	if endpoints, _ := registry.Lookup("LOCAL"); endpoints[EndpointBetting].Url == "http://other/" {
+		t.Error("Register kept the endpoints of the caller")
+	}
+}
+
+func TestFakeListEventTypes(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listEventTypes/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Authentication") != "token" || r.Header.Get("X-Application") != "delayed" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `[{"eventType":{"id":"1","name":"Soccer"},"marketCount":10}]`)
+	})
+	session := newFakeSession(t, server)
+	res, err := session.ListEventTypes(new(MarketFilter))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(res) != 1 || res[0].EventType.Name != "Soccer" {
+		t.Error("Unexpected result")
+	}
+}
+
+func TestFakeAPIError(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listMarketBook/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"faultcode":"Client","faultstring":"DSC-0018","detail":{"APINGException":{"errorCode":"TOO_MUCH_DATA","requestUUID":"uuid"},"exceptionname":"APINGException"}}`)
+	})
+	session := newFakeSession(t, server)
+	_, err := session.ListMarketBook([]string{"1.1"}, new(ProjectionParams))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatal("Expected an APIError")
+	}
+	if apiErr.ErrorCode != ErrorCodeTooMuchData || apiErr.Method != "listMarketBook" {
+		t.Error("Unexpected error: " + apiErr.Error())
+	}
+
+	mux.HandleFunc("/api/certlogin", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	err = session.LoginNonInteractive()
+	if !errors.As(err, &apiErr) || apiErr.Method != EndpointCertLogin {
+		t.Errorf("Expected an APIError of certLogin, got %v", err)
+	}
+	mux.HandleFunc("/api/certlogin", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"loginStatus":"INVALID_USERNAME_OR_PASSWORD"}`)
+	})
+	err = session.LoginNonInteractive()
+	if !errors.As(err, &apiErr) || apiErr.Method != EndpointCertLogin || apiErr.ErrorCode != "INVALID_USERNAME_OR_PASSWORD" {
+		t.Errorf("Expected an APIError of certLogin, got %v", err)
+	}
+}
+
+func TestFakeRetry(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var books, orders int
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listMarketBook/", func(w http.ResponseWriter, r *http.Request) {
+		if books++; books < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `[{"marketId":"1.1"}]`)
+	})
+	mux.HandleFunc("/exchange/betting/rest/v1.0/placeOrders/", func(w http.ResponseWriter, r *http.Request) {
+		orders++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	session := newFakeSession(t, server)
+	session.config.RetryPolicy = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryPlaceOrders: true}
+
+	res, err := session.ListMarketBook([]string{"1.1"}, new(ProjectionParams))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(res) != 1 || books != 3 {
+		t.Errorf("Expected success at the third attempt, got %d attempts", books)
+	}
+
+	if _, err := session.PlaceOrders("1.1", nil, "", "", 0); err == nil {
+		t.Error("Expected an error")
+	}
+	if orders != 1 {
+		t.Errorf("placeOrders without customerRef retried %d times", orders-1)
+	}
+	orders = 0
+	session.PlaceOrders("1.1", nil, "ref", "", 0)
+	if orders != 3 {
+		t.Errorf("placeOrders with customerRef attempted %d times", orders)
+	}
+}
+
+func TestFakeRetryCertificates(t *testing.T) {
+	_, mux := newFakeServer(t)
+	server := httptest.NewUnstartedServer(mux)
+	var mu sync.Mutex
+	connections := 0
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	other := sha256.Sum256([]byte("other"))
+
+	configs := map[string]*Config{
+		"untrusted": {},
+		"unpinned":  {RootCAs: roots, PinnedSPKI: []string{base64.StdEncoding.EncodeToString(other[:])}},
+	}
+	for name, config := range configs {
+		config.Username = "username"
+		config.Password = "password"
+		config.CertificateProvider = CertificateProviderFunc(func() (*tls.Certificate, error) {
+			return &tls.Certificate{}, nil
+		})
+		config.Endpoints = NewEndpoints(server.URL)
+		config.RetryPolicy = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+		session, err := NewSession(config)
+		if err != nil {
+			t.Fatal(name + ": " + err.Error())
+		}
+		mu.Lock()
+		connections = 0
+		mu.Unlock()
+		if _, err := session.GetAccountFunds(); err == nil {
+			t.Error(name + ": expected an error")
+		}
+		mu.Lock()
+		if connections != 1 {
+			t.Errorf("%s: retried %d times", name, connections-1)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestFakeRetryCancelled(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listMarketBook/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	session := newFakeSession(t, server)
+	// Cancelled while waiting before the second attempt.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	session.config.RetryPolicy = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}
+	if _, err := session.ListMarketBookContext(ctx, []string{"1.1"}, new(ProjectionParams)); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	retryable := []error{
+		&url.Error{Op: "Post", URL: "url", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}},
+		&url.Error{Op: "Post", URL: "url", Err: io.ErrUnexpectedEOF},
+		&APIError{StatusCode: http.StatusServiceUnavailable},
+		&APIError{StatusCode: http.StatusBadRequest, ErrorCode: ErrorCodeTooManyRequests},
+	}
+	for _, err := range retryable {
+		if !IsRetryable(err) {
+			t.Error("Expected retryable: " + err.Error())
+		}
+	}
+	permanent := []error{
+		context.Canceled,
+		errors.New("Invalid endpoint key: xx"),
+		&url.Error{Op: "Post", URL: "url", Err: x509.UnknownAuthorityError{}},
+		&APIError{StatusCode: http.StatusBadRequest, ErrorCode: ErrorCodeInvalidSessionInformation},
+		&APIError{StatusCode: http.StatusNotFound},
+	}
+	for _, err := range permanent {
+		if IsRetryable(err) {
+			t.Error("Expected not retryable: " + err.Error())
+		}
+	}
+}
+
+func TestMarketBookWeight(t *testing.T) {
+	cases := []struct {
+		projection *PriceProjection
+		weight     int
+	}{
+		{nil, 2},
+		{&PriceProjection{PriceData: []PriceDataVal{PriceDataEXBestOffers}}, 5},
+		{&PriceProjection{PriceData: []PriceDataVal{PriceDataEXAllOffers, PriceDataEXTraded}}, 32},
+		{&PriceProjection{PriceData: []PriceDataVal{PriceDataEXBestOffers, PriceDataEXTraded}}, 20},
+		{&PriceProjection{
+			PriceData:             []PriceDataVal{PriceDataEXBestOffers},
+			ExBestOffersOverrides: &ExBestOffersOverrides{BestPricesDepth: 6},
+		}, 10},
+	}
+	for _, c := range cases {
+		if weight := MarketBookWeight(c.projection, 1); weight != c.weight {
+			t.Errorf("Expected weight %d, got %d", c.weight, weight)
+		}
+	}
+}
+
+func TestFakeListMarketBookBatches(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var requests int
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listMarketBook/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var params Params
+		json.NewDecoder(r.Body).Decode(&params)
+		if MarketBookWeight(params.PriceProjection, len(params.MarketIds)) > MaxRequestWeight {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"detail":{"APINGException":{"errorCode":"TOO_MUCH_DATA"},"exceptionname":"APINGException"}}`)
+			return
+		}
+		var books []MarketBook
+		for _, marketId := range params.MarketIds {
+			books = append(books, MarketBook{MarketId: marketId})
+		}
+		json.NewEncoder(w).Encode(books)
+	})
+	session := newFakeSession(t, server)
+
+	var marketIds []string
+	for i := 0; i < 25; i++ {
+		marketIds = append(marketIds, fmt.Sprintf("1.%d", i))
+	}
+	projection := &ProjectionParams{PriceProjection: &PriceProjection{
+		PriceData: []PriceDataVal{PriceDataEXAllOffers, PriceDataEXTraded},
+	}}
+	res, err := session.ListMarketBook(marketIds, projection)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if requests != 5 || len(res) != len(marketIds) {
+		t.Errorf("Expected 5 requests and %d books, got %d and %d", len(marketIds), requests, len(res))
+	}
+	for i, book := range res {
+		if book.MarketId != marketIds[i] {
+			t.Error("Books out of order")
+		}
+	}
+}
+
+func TestFakeListMarketCatalogueBatches(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var requests int
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listMarketCatalogue/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var params Params
+		json.NewDecoder(r.Body).Decode(&params)
+		markets := params.MaxResults
+		if params.MarketFilter != nil && len(params.MarketFilter.MarketIds) > 0 && len(params.MarketFilter.MarketIds) < markets {
+			markets = len(params.MarketFilter.MarketIds)
+		}
+		if MarketCatalogueWeight(params.MarketProjection, markets) > MaxRequestWeight {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"detail":{"APINGException":{"errorCode":"TOO_MUCH_DATA"},"exceptionname":"APINGException"}}`)
+			return
+		}
+		var catalogues []MarketCatalogue
+		for i := 0; i < markets; i++ {
+			marketId := fmt.Sprintf("2.%d", i)
+			if params.MarketFilter != nil && len(params.MarketFilter.MarketIds) > 0 {
+				marketId = params.MarketFilter.MarketIds[i]
+			}
+			catalogues = append(catalogues, MarketCatalogue{MarketId: marketId})
+		}
+		json.NewEncoder(w).Encode(catalogues)
+	})
+	session := newFakeSession(t, server)
+
+	projection := &ProjectionParams{MarketProjection: []MarketProjVal{
+		MarketProjectionMarketDescription, MarketProjectionRunnerMetadata,
+	}}
+	var marketIds []string
+	for i := 0; i < 250; i++ {
+		marketIds = append(marketIds, fmt.Sprintf("1.%d", i))
+	}
+	tests := []struct {
+		name       string
+		filter     *MarketFilter
+		maxResults int
+		requests   int
+		markets    int
+	}{
+		{"few results", &MarketFilter{MarketIds: marketIds}, 10, 1, 10},
+		{"many ids", &MarketFilter{MarketIds: marketIds}, 1000, 3, 250},
+	}
+	for _, test := range tests {
+		requests = 0
+		res, err := session.ListMarketCatalogue(test.filter, test.maxResults, projection)
+		if err != nil {
+			t.Fatal(test.name + ": " + err.Error())
+		}
+		if requests != test.requests || len(res) != test.markets {
+			t.Errorf("%s: expected %d requests and %d markets, got %d and %d",
+				test.name, test.requests, test.markets, requests, len(res))
+		}
+		for i, catalogue := range res {
+			if catalogue.MarketId != marketIds[i] {
+				t.Error(test.name + ": markets out of order")
+			}
+		}
+	}
+
+	// Without ids to split by, the request fails rather than returning
+	// fewer markets than asked.
+	requests = 0
+	_, err := session.ListMarketCatalogue(new(MarketFilter), 1000, projection)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != ErrorCodeTooMuchData || requests != 0 {
+		t.Errorf("Expected TOO_MUCH_DATA without request, got %v after %d requests", err, requests)
+	}
+	if _, err := session.ListMarketCatalogue(new(MarketFilter), 100, projection); err != nil || requests != 1 {
+		t.Errorf("Expected a single request, got %v after %d requests", err, requests)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(1000, 2)
+	start := time.Now()
+	for i := 0; i < 12; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 9*time.Millisecond {
+		t.Errorf("Expected the limiter to wait, took %s", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewRateLimiter(0.001, 1).Wait(ctx); err != nil {
+		t.Error("The first request should not wait")
+	}
+}
+
+func TestFakeAutoRelogin(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var mu sync.Mutex
+	logins := 0
+	mux.HandleFunc("/api/certlogin", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		logins++
+		fmt.Fprintf(w, `{"loginStatus":"SUCCESS","sessionToken":"token%d"}`, logins)
+	})
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listEventTypes/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Authentication") == "token1" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"faultcode":"Client","faultstring":"ANGX-0003","detail":{"APINGException":{"requestUUID":"uuid","errorCode":"INVALID_SESSION_INFORMATION","errorDetails":""},"exceptionname":"APINGException"}}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	session := newFakeSession(t, server)
+	session.config.AutoRelogin = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := session.ListEventTypes(new(MarketFilter)); err != nil {
+				t.Error(err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if logins != 2 {
+		t.Errorf("Expected a single new login, got %d", logins-1)
+	}
+}
+
+func TestFakeKeepAlive(t *testing.T) {
+	server, mux := newFakeServer(t)
+	keepAlives := make(chan struct{}, 100)
+	mux.HandleFunc("/api/keepAlive", func(w http.ResponseWriter, r *http.Request) {
+		keepAlives <- struct{}{}
+		fmt.Fprint(w, `{"token":"token","product":"app","status":"SUCCESS","error":""}`)
+	})
+	config := &Config{
+		Username:          "username",
+		Password:          "password",
+		Endpoints:         NewEndpoints(server.URL),
+		HTTPClient:        server.Client(),
+		KeepAliveInterval: 5 * time.Millisecond,
+		OnKeepAliveError:  func(err error) { t.Error(err.Error()) },
+	}
+	session, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := session.LoginNonInteractive(); err != nil {
+		t.Fatal(err.Error())
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-keepAlives:
+		case <-time.After(time.Second):
+			t.Fatal("No keep alive")
+		}
+	}
+	session.Close()
+	session.Close()
+	// Let an in-flight keep alive complete before checking it stopped.
+	time.Sleep(20 * time.Millisecond)
+	for len(keepAlives) > 0 {
+		<-keepAlives
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(keepAlives) != 0 {
+		t.Error("Keep alive not stopped")
+	}
+}
+
+// Run with -race: pollers share the session with logins, keep alives and
+// switches between live and delayed data.
+func TestFakeConcurrentUse(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/api/keepAlive", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token":"token","product":"app","status":"SUCCESS","error":""}`)
+	})
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listMarketBook/", func(w http.ResponseWriter, r *http.Request) {
+		appKey := r.Header.Get("X-Application")
+		if appKey != "live" && appKey != "delayed" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"detail":{"APINGException":{"errorCode":"INVALID_APP_KEY"},"exceptionname":"APINGException"}}`)
+			return
+		}
+		fmt.Fprintf(w, `[{"marketId":"1.1","isMarketDataDelayed":%t}]`, appKey == "delayed")
+	})
+	session := newFakeSession(t, server)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				var err error
+				switch i % 4 {
+				case 0:
+					err = session.LoginNonInteractive()
+				case 1:
+					err = session.KeepAlive()
+				case 2:
+					session.SetLive(j%2 == 0)
+					session.IsLive()
+				default:
+					_, err = session.ListMarketBook([]string{"1.1"}, new(ProjectionParams))
+				}
+				if err != nil {
+					t.Error(err.Error())
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestFakeLoginInteractive(t *testing.T) {
+	server, mux := newFakeServer(t)
+	status, loginError := "SUCCESS", ""
+	mux.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Application") != "appkey" || r.FormValue("password") != "pass&word" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"token":"token","product":"appkey","status":"%s","error":"%s"}`, status, loginError)
+	})
+	config := &Config{
+		Username:    "username",
+		Password:    "pass&word",
+		Interactive: true,
+		AppKey:      "appkey",
+		Endpoints:   NewEndpoints(server.URL),
+	}
+	session, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := session.LoginInteractive(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if session.getToken() != "token" {
+		t.Error("Token not set")
+	}
+
+	status, loginError = "FAIL", "ACCOUNT_PENDING_PASSWORD_CHANGE"
+	err = session.LoginInteractive()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode != ErrorCodeAccountPendingPasswordChange || apiErr.Method != EndpointLogin {
+		t.Errorf("Expected ACCOUNT_PENDING_PASSWORD_CHANGE, got %v", err)
+	}
+}
+
+// Generates a self signed client certificate as PEM blocks.
+func newClientCertificate(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "username"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return certPEM, keyPEM
+}
+
+func TestParsePKCS12(t *testing.T) {
+	var certs []tls.Certificate
+	for i := 0; i < 2; i++ {
+		certPEM, keyPEM := newClientCertificate(t)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			t.Fatal(err.Error())
+		}
+		certs = append(certs, cert)
+	}
+	// A bundle listing a CA before the certificate of the key.
+	p12, err := pkcs12.Modern.Encode(certs[0].PrivateKey, certs[1].Leaf, []*x509.Certificate{certs[0].Leaf}, "secret")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cert, err := parsePKCS12(p12, "secret")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(cert.Certificate) != 2 || !cert.Leaf.Equal(certs[0].Leaf) || !bytes.Equal(cert.Certificate[1], certs[1].Leaf.Raw) {
+		t.Error("Unexpected certificate chain")
+	}
+	if _, err := parsePKCS12(p12, "wrong"); err == nil {
+		t.Error("Expected an error for a wrong password")
+	}
+}
+
+func TestFakeInMemoryCertificate(t *testing.T) {
+	_, mux := newFakeServer(t)
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	certPEM, keyPEM := newClientCertificate(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	p12, err := pkcs12.Modern.Encode(cert.PrivateKey, leaf, nil, "secret")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	configs := map[string]*Config{
+		"PEM":     {CertPEM: certPEM, KeyPEM: keyPEM},
+		"PKCS#12": {PKCS12: p12, PKCS12Password: "secret"},
+		"provider": {CertificateProvider: CertificateProviderFunc(func() (*tls.Certificate, error) {
+			return &cert, nil
+		})},
+	}
+	for name, config := range configs {
+		config.Username = "username"
+		config.Password = "password"
+		config.Endpoints = NewEndpoints(server.URL)
+		config.RootCAs = roots
+		session, err := NewSession(config)
+		if err != nil {
+			t.Fatal(name + ": " + err.Error())
+		}
+		if err := session.LoginNonInteractive(); err != nil {
+			t.Error(name + ": " + err.Error())
+		}
+	}
+
+	config := &Config{Username: "username", Password: "password", PKCS12: []byte("invalid")}
+	if _, err := NewSession(config); err == nil {
+		t.Error("Expected an error for an invalid PKCS#12 bundle")
+	}
+}
+
+func TestFakeResume(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/api/keepAlive", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Authentication") != "token" {
+			fmt.Fprint(w, `{"token":"","product":"app","status":"FAIL","error":"NO_SESSION"}`)
+			return
+		}
+		fmt.Fprint(w, `{"token":"token","product":"app","status":"SUCCESS","error":""}`)
+	})
+	store := &FileTokenStore{Path: t.TempDir() + "/session.json"}
+	config := &Config{
+		Username:   "username",
+		Password:   "password",
+		Endpoints:  NewEndpoints(server.URL),
+		HTTPClient: server.Client(),
+		TokenStore: store,
+	}
+	session, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := session.Resume(); err == nil {
+		t.Error("Expected an error with an empty store")
+	}
+	if err := session.LoginNonInteractive(); err != nil {
+		t.Fatal(err.Error())
+	}
+	exported := session.Export()
+
+	resumed, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := resumed.Resume(); err != nil {
+		t.Fatal(err.Error())
+	}
+	state := resumed.Export()
+	if state.Token != exported.Token || !state.IssuedAt.Equal(exported.IssuedAt) || state.LiveAppKey != "live" || state.DelayedAppKey != "delayed" {
+		t.Errorf("Unexpected state %+v, expected %+v", state, exported)
+	}
+
+	state.Token = "expired"
+	if err := store.Save(&state); err != nil {
+		t.Fatal(err.Error())
+	}
+	expired, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var apiErr *APIError
+	if err := expired.Resume(); !errors.As(err, &apiErr) || apiErr.ErrorCode != ErrorCodeNoSession {
+		t.Errorf("Expected NO_SESSION, got %v", err)
+	}
+	if expired.Export().Token != "" {
+		t.Error("Expired token kept")
+	}
+}
+
+func TestSelectAppKeys(t *testing.T) {
+	apps := []DeveloperApp{
+		{AppName: "first", AppVersions: []DeveloperAppVersion{
+			{Version: "1.0-DELAY", ApplicationKey: "first-delayed", DelayData: true, Active: true},
+			{Version: "1.0", ApplicationKey: "first-live"},
+		}},
+		{AppName: "second", AppVersions: []DeveloperAppVersion{
+			{Version: "1.0", ApplicationKey: "old-delayed", DelayData: true, Active: true},
+			{Version: "2.0-DELAY", ApplicationKey: "new-delayed", DelayData: true, Active: true},
+			{Version: "2.0", ApplicationKey: "new-live", Active: true},
+			{Version: "3.0", ApplicationKey: "inactive-live"},
+			{Version: "3.0-DELAY", ApplicationKey: "inactive-delayed", DelayData: true},
+		}},
+	}
+	tests := []struct {
+		name, version, live, delayed string
+	}{
+		{"", "", "", "first-delayed"},
+		{"second", "", "new-live", "new-delayed"},
+		{"second", "1.0", "", "old-delayed"},
+		{"second", "2.0", "new-live", "new-delayed"},
+	}
+	for _, test := range tests {
+		live, delayed, err := selectAppKeys(apps, test.name, test.version)
+		if err != nil || live != test.live || delayed != test.delayed {
+			t.Errorf("%s %s: got %q %q %v", test.name, test.version, live, delayed, err)
+		}
+	}
+	if _, _, err := selectAppKeys(apps, "third", ""); err == nil {
+		t.Error("Expected an error for an unknown app")
+	}
+	if _, _, err := selectAppKeys(apps, "second", "3.0"); err == nil {
+		t.Error("Expected an error for an inactive version")
+	}
+
+	// The first app has no active key.
+	inactive := DeveloperApp{AppName: "inactive", AppVersions: []DeveloperAppVersion{
+		{Version: "1.0", ApplicationKey: "inactive-live"},
+	}}
+	live, delayed, err := selectAppKeys(append([]DeveloperApp{inactive}, apps...), "", "")
+	if err != nil || live != "" || delayed != "first-delayed" {
+		t.Errorf("Expected the keys of the first active app, got %q %q %v", live, delayed, err)
+	}
+	if _, _, err := selectAppKeys([]DeveloperApp{inactive}, "", ""); err == nil {
+		t.Error("Expected an error without active keys")
+	}
+}
+
+func TestFakeConfigAppKey(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/exchange/account/rest/v1.0/getDeveloperAppKeys/", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("App keys fetched")
+	})
+	config := &Config{
+		Username:   "username",
+		Password:   "password",
+		AppKey:     "appkey",
+		Endpoints:  NewEndpoints(server.URL),
+		HTTPClient: server.Client(),
+	}
+	session, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := session.LoginNonInteractive(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if session.getAppKey(context.Background()) != "appkey" {
+		t.Error("Delayed key not set")
+	}
+	session.SetLive(true)
+	if session.getAppKey(context.Background()) != "appkey" {
+		t.Error("Live key not set")
+	}
+}
+
+func TestFakeWithLiveData(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listMarketBook/", func(w http.ResponseWriter, r *http.Request) {
+		delayed := r.Header.Get("X-Application") == "delayed"
+		fmt.Fprintf(w, `[{"marketId":"1.1","isMarketDataDelayed":%t}]`, delayed)
+	})
+	session := newFakeSession(t, server)
+	books, err := session.ListMarketBook([]string{"1.1"}, new(ProjectionParams))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := RequireLiveData(books); !errors.Is(err, ErrMarketDataDelayed) {
+		t.Errorf("Expected ErrMarketDataDelayed, got %v", err)
+	}
+	books, err = session.ListMarketBookContext(WithLiveData(context.Background()), []string{"1.1"}, new(ProjectionParams))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := RequireLiveData(books); err != nil {
+		t.Error(err.Error())
+	}
+
+	session.SetLive(true)
+	if session.getAppKey(WithDelayedData(context.Background())) != "delayed" {
+		t.Error("Delayed data not selected")
+	}
+	if session.getAppKey(context.Background()) != "live" {
+		t.Error("Live data not selected")
+	}
+}
+
+func TestFakeIterateAccountStatement(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/exchange/account/rest/v1.0/getAccountStatement/", func(w http.ResponseWriter, r *http.Request) {
+		var params accountStatementParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil || params.IncludeItem != IncludeItemExchange {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if params.FromRecord == 0 {
+			fmt.Fprint(w, `{"accountStatement":[
+				{"refId":"1","itemDate":"2026-10-01T12:00:00.000Z","amount":2.5,"balance":102.5,
+				 "itemClass":"UNKNOWN","legacyData":{"avgPrice":2.5,"betSize":1,"winLose":"RESULT_WON"}},
+				{"refId":"2","amount":-1,"balance":101.5}],"moreAvailable":true}`)
+			return
+		}
+		fmt.Fprintf(w, `{"accountStatement":[{"refId":"%d","amount":1,"balance":102.5}],"moreAvailable":false}`, params.FromRecord+1)
+	})
+	session := newFakeSession(t, server)
+
+	it := session.IterateAccountStatement(&AccountStatementParams{IncludeItem: IncludeItemExchange, RecordCount: 2})
+	var refIds []string
+	for it.Next() {
+		refIds = append(refIds, it.Item().RefId)
+		if it.Item().RefId == "1" && it.Item().LegacyData.WinLose != "RESULT_WON" {
+			t.Errorf("Unexpected legacy data %+v", it.Item().LegacyData)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(refIds) != 3 || refIds[2] != "3" {
+		t.Errorf("Unexpected items %v", refIds)
+	}
+}
+
+// Checks that body is the same JSON as want, whatever the order of the keys.
+func assertJSON(t *testing.T, body []byte, want string) {
+	t.Helper()
+	var got, expected interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected JSON %s, expected %s", body, want)
+	}
+}
+
+// Answers method with response, passing the body of the requests to check.
+func handleBetting(mux *fakeMux, method, response string, check func(body []byte)) {
+	mux.HandleFunc("/exchange/betting/rest/v1.0/"+method+"/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		check(body)
+		fmt.Fprint(w, response)
+	})
+}
+
+func TestFakePlaceOrders(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var body []byte
+	handleBetting(mux, "placeOrders", `{"customerRef":"ref","status":"SUCCESS","marketId":"1.1","instructionReports":[
+		{"status":"SUCCESS","orderStatus":"EXECUTION_COMPLETE","betId":"31","placedDate":"2026-10-01T12:00:00.000Z",
+		 "averagePriceMatched":2.5,"sizeMatched":10,
+		 "instruction":{"orderType":"LIMIT","selectionId":47972,"side":"BACK","limitOrder":{"size":10,"price":2.5}}},
+		{"status":"FAILURE","errorCode":"INVALID_BET_SIZE",
+		 "instruction":{"orderType":"MARKET_ON_CLOSE","selectionId":47973,"side":"LAY","marketOnCloseOrder":{"liability":1}}}]}`,
+		func(b []byte) { body = b })
+	session := newFakeSession(t, server)
+
+	instructions := []PlaceInstruction{
+		{OrderType: OrderTypeLimit, SelectionId: 47972, Side: SideBack,
+			LimitOrder: &LimitOrder{Size: 10, Price: 2.5, PersistenceType: PersistenceTypeLapse}},
+		{OrderType: OrderTypeLimitOnClose, SelectionId: 47973, Side: SideLay,
+			LimitOnCloseOrder: &LimitOnCloseOrder{Liability: 20, Price: 3}},
+		{OrderType: OrderTypeMarketOnClose, SelectionId: 47973, Side: SideLay, CustomerOrderRef: "order",
+			MarketOnCloseOrder: &MarketOnCloseOrder{Liability: 1}},
+	}
+	report, err := session.PlaceOrders("1.1", instructions, "ref", "strategy", 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"marketId":"1.1","customerRef":"ref","customerStrategyRef":"strategy","instructions":[
+		{"orderType":"LIMIT","selectionId":47972,"side":"BACK","limitOrder":{"size":10,"price":2.5,"persistenceType":"LAPSE"}},
+		{"orderType":"LIMIT_ON_CLOSE","selectionId":47973,"side":"LAY","limitOnCloseOrder":{"liability":20,"price":3}},
+		{"orderType":"MARKET_ON_CLOSE","selectionId":47973,"side":"LAY","customerOrderRef":"order","marketOnCloseOrder":{"liability":1}}]}`)
+
+	if report.Status != ExecutionReportStatusSuccess || len(report.InstructionReports) != 2 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	placed, failed := report.InstructionReports[0], report.InstructionReports[1]
+	if placed.BetId != "31" || placed.AveragePriceMatched != 2.5 || placed.SizeMatched != 10 ||
+		placed.OrderStatus != OrderStatusExecutionComplete || placed.PlacedDate.IsZero() ||
+		placed.Instruction.LimitOrder == nil || placed.Instruction.LimitOrder.Price != 2.5 {
+		t.Errorf("Unexpected report %+v", placed)
+	}
+	if failed.Status != InstructionReportStatusFailure || failed.ErrorCode != InstructionReportErrorInvalidBetSize {
+		t.Errorf("Unexpected report %+v", failed)
+	}
+
+	if _, err := session.PlaceOrders("1.1", instructions[:1], "", "", 7); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"marketId":"1.1","marketVersion":{"version":7},"instructions":[
+		{"orderType":"LIMIT","selectionId":47972,"side":"BACK","limitOrder":{"size":10,"price":2.5,"persistenceType":"LAPSE"}}]}`)
+}
+
+func TestFakeCancelReplaceUpdateOrders(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var body []byte
+	check := func(b []byte) { body = b }
+	handleBetting(mux, "cancelOrders", `{"status":"SUCCESS","instructionReports":[]}`, check)
+	handleBetting(mux, "replaceOrders", `{"status":"SUCCESS","marketId":"1.1","instructionReports":[
+		{"status":"SUCCESS",
+		 "cancelInstructionReport":{"status":"SUCCESS","instruction":{"betId":"31"},"sizeCancelled":10,"cancelledDate":"2026-10-01T12:00:00.000Z"},
+		 "placeInstructionReport":{"status":"SUCCESS","orderStatus":"EXECUTABLE","betId":"32","sizeMatched":0,
+		  "instruction":{"orderType":"LIMIT","selectionId":47972,"side":"BACK","limitOrder":{"size":10,"price":3}}}}]}`, check)
+	handleBetting(mux, "updateOrders", `{"status":"SUCCESS","marketId":"1.1","instructionReports":[
+		{"status":"SUCCESS","instruction":{"betId":"32","newPersistenceType":"PERSIST"}}]}`, check)
+	session := newFakeSession(t, server)
+
+	if _, err := session.CancelOrders("", nil, ""); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{}`)
+	if _, err := session.CancelOrders("1.1", []CancelInstruction{{BetId: "31", SizeReduction: 2}, {BetId: "33"}}, "ref"); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"marketId":"1.1","customerRef":"ref","instructions":[{"betId":"31","sizeReduction":2},{"betId":"33"}]}`)
+
+	replaced, err := session.ReplaceOrders("1.1", []ReplaceInstruction{{BetId: "31", NewPrice: 3}}, "", 7)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"marketId":"1.1","marketVersion":{"version":7},"instructions":[{"betId":"31","newPrice":3}]}`)
+	if len(replaced.InstructionReports) != 1 {
+		t.Fatalf("Unexpected report %+v", replaced)
+	}
+	cancel := replaced.InstructionReports[0].CancelInstructionReport
+	place := replaced.InstructionReports[0].PlaceInstructionReport
+	if cancel == nil || cancel.SizeCancelled != 10 || cancel.Instruction == nil || cancel.Instruction.BetId != "31" || cancel.CancelledDate.IsZero() {
+		t.Errorf("Unexpected cancel report %+v", cancel)
+	}
+	if place == nil || place.BetId != "32" || place.OrderStatus != OrderStatusExecutable || place.Instruction.LimitOrder.Price != 3 {
+		t.Errorf("Unexpected place report %+v", place)
+	}
+
+	updated, err := session.UpdateOrders("1.1", []UpdateInstruction{{BetId: "32", NewPersistenceType: PersistenceTypePersist}}, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"marketId":"1.1","instructions":[{"betId":"32","newPersistenceType":"PERSIST"}]}`)
+	if len(updated.InstructionReports) != 1 || updated.InstructionReports[0].Instruction.NewPersistenceType != PersistenceTypePersist {
+		t.Errorf("Unexpected report %+v", updated)
+	}
+}
+
+func TestFakeIterateOrders(t *testing.T) {
+	server, mux := newFakeServer(t)
+	// Three orders, served two per page from fromRecord.
+	page := func(r *http.Request, key string) string {
+		var params struct{ FromRecord int }
+		json.NewDecoder(r.Body).Decode(&params)
+		var orders []string
+		for i := params.FromRecord; i < 3 && i < params.FromRecord+2; i++ {
+			orders = append(orders, fmt.Sprintf(`{"betId":"%d"}`, i))
+		}
+		more := params.FromRecord+2 < 3
+		return fmt.Sprintf(`{"%s":[%s],"moreAvailable":%t}`, key, strings.Join(orders, ","), more)
+	}
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listCurrentOrders/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page(r, "currentOrders"))
+	})
+	mux.HandleFunc("/exchange/betting/rest/v1.0/listClearedOrders/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page(r, "clearedOrders"))
+	})
+	session := newFakeSession(t, server)
+
+	current := session.IterateCurrentOrders(&CurrentOrdersParams{FromRecord: 1})
+	var betIds []string
+	for current.Next() {
+		betIds = append(betIds, current.Order().BetId)
+	}
+	if current.Err() != nil || strings.Join(betIds, ",") != "1,2" {
+		t.Errorf("Unexpected current orders %v, %v", betIds, current.Err())
+	}
+	cleared := session.IterateClearedOrders(BetStatusSettled, nil)
+	betIds = nil
+	for cleared.Next() {
+		betIds = append(betIds, cleared.Order().BetId)
+	}
+	if cleared.Err() != nil || strings.Join(betIds, ",") != "0,1,2" {
+		t.Errorf("Unexpected cleared orders %v, %v", betIds, cleared.Err())
+	}
+}
+
+func TestFakeMarketFilter(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var body []byte
+	handleBetting(mux, "listEventTypes", `[]`, func(b []byte) { body = b })
+	session := newFakeSession(t, server)
+
+	filter := &MarketFilter{
+		InPlayOnly:      BoolPtr(false),
+		BspOnly:         BoolPtr(true),
+		MarketStartTime: &TimeRange{From: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	if _, err := session.ListEventTypes(filter); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","filter":{"inPlayOnly":false,"bspOnly":true,
+		"marketStartTime":{"from":"2026-10-01T12:00:00Z"}}}`)
+}
+
+func TestFakeListMarketBookWithOptions(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var body []byte
+	handleBetting(mux, "listMarketBook", `[{"marketId":"1.1"}]`, func(b []byte) { body = b })
+	session := newFakeSession(t, server)
+
+	options := &MarketBookOptions{
+		ProjectionParams:              ProjectionParams{OrderProjection: OrderProjectionExecutable},
+		IncludeOverallPosition:        BoolPtr(false),
+		PartitionMatchedByStrategyRef: true,
+		CustomerStrategyRefs:          []string{"strategy"},
+		CurrencyCode:                  "EUR",
+		BetIds:                        []string{"31"},
+	}
+	if _, err := session.ListMarketBookWithOptions([]string{"1.1"}, options); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","marketIds":["1.1"],"orderProjection":"EXECUTABLE",
+		"includeOverallPosition":false,"partitionMatchedByStrategyRef":true,
+		"customerStrategyRefs":["strategy"],"currencyCode":"EUR","betIds":["31"]}`)
+
+	options = &MarketBookOptions{MatchedSince: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+	if _, err := session.ListMarketBookWithOptions([]string{"1.1"}, options); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","marketIds":["1.1"],"matchedSince":"2026-10-01T12:00:00Z"}`)
+}
+
+func TestFakeListTimeRangesAndVenues(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var body []byte
+	check := func(b []byte) { body = b }
+	handleBetting(mux, "listTimeRanges", `[{"timeRange":{"from":"2026-10-01T00:00:00.000Z","to":"2026-10-02T00:00:00.000Z"},"marketCount":12}]`, check)
+	handleBetting(mux, "listVenues", `[{"venue":"Ascot","marketCount":7}]`, check)
+	session := newFakeSession(t, server)
+
+	filter := &MarketFilter{EventTypeIds: []string{"7"}}
+	ranges, err := session.ListTimeRanges(filter, TimeGranularityDays)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","filter":{"eventTypeIds":["7"]},"granularity":"DAYS"}`)
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	if len(ranges) != 1 || !ranges[0].TimeRange.From.Equal(from) ||
+		!ranges[0].TimeRange.To.Equal(from.AddDate(0, 0, 1)) || ranges[0].MarketCount != 12 {
+		t.Errorf("Unexpected time ranges %+v", ranges)
+	}
+
+	venues, err := session.ListVenues(filter)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","filter":{"eventTypeIds":["7"]}}`)
+	if len(venues) != 1 || venues[0].Venue != "Ascot" || venues[0].MarketCount != 7 {
+		t.Errorf("Unexpected venues %+v", venues)
+	}
+}
+
+func TestFakeListMarketProfitAndLoss(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var body []byte
+	handleBetting(mux, "listMarketProfitAndLoss", `[{"marketId":"1.1","commissionApplied":0.05,
+		"profitAndLosses":[{"selectionId":47972,"ifWin":15.5},{"selectionId":47973,"ifWin":-10}]}]`,
+		func(b []byte) { body = b })
+	session := newFakeSession(t, server)
+
+	results, err := session.ListMarketProfitAndLoss([]string{"1.1"}, true, false, true)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","marketIds":["1.1"],"includeSettledBets":true,"netOfCommission":true}`)
+	if len(results) != 1 || results[0].CommissionApplied != 0.05 || len(results[0].ProfitAndLosses) != 2 {
+		t.Fatalf("Unexpected profit and loss %+v", results)
+	}
+	if pl := results[0].ProfitAndLosses[1]; pl.SelectionId != 47973 || pl.IfWin != -10 {
+		t.Errorf("Unexpected runner profit and loss %+v", pl)
+	}
+}
+
+func TestFakeListRunnerBook(t *testing.T) {
+	server, mux := newFakeServer(t)
+	var body []byte
+	handleBetting(mux, "listRunnerBook", `[{"marketId":"1.1","runners":[{"selectionId":47972,"handicap":-1.5,
+		"ex":{"availableToBack":[{"price":2.5,"size":100}]}}]}]`, func(b []byte) { body = b })
+	session := newFakeSession(t, server)
+
+	options := &MarketBookOptions{ProjectionParams: ProjectionParams{
+		PriceProjection: &PriceProjection{PriceData: []PriceDataVal{PriceDataEXBestOffers}},
+	}}
+	books, err := session.ListRunnerBook("1.1", 47972, -1.5, options)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","marketId":"1.1","selectionId":47972,"handicap":-1.5,
+		"priceProjection":{"priceData":["EX_BEST_OFFERS"]}}`)
+	if len(books) != 1 || len(books[0].Runners) != 1 {
+		t.Fatalf("Unexpected books %+v", books)
+	}
+	runner := books[0].Runners[0]
+	if runner.SelectionID != 47972 || len(runner.ExchangePrices.AvailableToBack) != 1 ||
+		runner.ExchangePrices.AvailableToBack[0].Price != 2.5 {
+		t.Errorf("Unexpected runner %+v", runner)
+	}
+
+	if _, err := session.ListRunnerBook("1.1", 47972, 0, nil); err != nil {
+		t.Fatal(err.Error())
+	}
+	assertJSON(t, body, `{"locale":"en","marketId":"1.1","selectionId":47972}`)
+}
+
+func TestFakeAccountGetEndpoint(t *testing.T) {
+	server, mux := newFakeServer(t)
+	mux.HandleFunc("/exchange/account/rest/v1.0/getDeveloperAppKeys/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.Header.Get("X-Application") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `[{"appName":"app","appVersions":[{"version":"1.0","applicationKey":"live","active":true}]}]`)
+	})
+	mux.HandleFunc("/exchange/account/rest/v1.0/getAccountFunds/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"availableToBetBalance":10}`)
+	})
+	// Account endpoints declared as GET, the former default.
+	endpoints := NewEndpoints(server.URL)
+	endpoints[EndpointAccount] = RequestSpecification{server.URL + "/exchange/account/rest/v1.0/", "GET"}
+	config := &Config{
+		Username:   "username",
+		Password:   "password",
+		Endpoints:  endpoints,
+		HTTPClient: server.Client(),
+	}
+	session, err := NewSession(config)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := session.LoginNonInteractive(); err != nil {
+		t.Fatal(err.Error())
+	}
+	funds, err := session.GetAccountFunds()
+	if err != nil || funds.AvailableToBetBalance != 10 {
+		t.Errorf("Unexpected funds %+v, %v", funds, err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2013 Alessandro De Donno
+
+// "Betfair API-NG Golang Library" is dual-licensed: for free software projects
+// please refer to GPLv3 (see declaration above), for commercial software
+// please contact the author.
+// If you are a contributor and need any clarification, please contact the
+// author.
+
+// For free software projects:
+
+// This file is part of "Betfair API-NG Golang Library".
+// "Betfair API-NG Golang Library" is free software: you can redistribute it
+// and/or modify it under the terms of the GNU General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+// "Betfair API-NG Golang Library" is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+// You should have received a copy of the GNU General Public License
+// along with "Betfair API-NG Golang Library".  If not, see
+// <http://www.gnu.org/licenses/>.
+
+package betfair
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// SessionState is what is needed to resume a session without logging in.
+type SessionState struct {
+	Token         string
+	LiveAppKey    string
+	DelayedAppKey string
+	IssuedAt      time.Time
+}
+
+// TokenStore persists the state of a session across process restarts.
+type TokenStore interface {
+	// Load returns nil when nothing was saved.
+	Load() (*SessionState, error)
+	Save(state *SessionState) error
+}
+
+// FileTokenStore keeps the session state in a JSON file, readable by its
+// owner only.
+type FileTokenStore struct {
+	Path string
+}
+
+// Load reads the session state from the file.
+func (f *FileTokenStore) Load() (*SessionState, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := new(SessionState)
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Save writes the session state to the file, replacing it atomically.
+func (f *FileTokenStore) Save(state *SessionState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// Export returns the current state of the session.
+func (s *Session) Export() SessionState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return SessionState{
+		Token:         s.token,
+		LiveAppKey:    s.appKeys[LIVE_DATA],
+		DelayedAppKey: s.appKeys[DELAY_DATA],
+		IssuedAt:      s.issuedAt,
+	}
+}
+
+// Import replaces the state of the session, without checking it. See Resume.
+func (s *Session) Import(state SessionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = state.Token
+	s.appKeys[LIVE_DATA] = state.LiveAppKey
+	s.appKeys[DELAY_DATA] = state.DelayedAppKey
+	s.issuedAt = state.IssuedAt
+}
+
+// Resume loads the session state from Config.TokenStore and checks with
+// Keep Alive that the token is still valid, instead of logging in.
+func (s *Session) Resume() error {
+	return s.ResumeContext(context.Background())
+}
+
+// ResumeContext is like Resume, but the requests are bound to ctx.
+func (s *Session) ResumeContext(ctx context.Context) error {
+	if s.config.TokenStore == nil {
+		return errors.New("Config.TokenStore is not set.")
+	}
+	state, err := s.config.TokenStore.Load()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return errors.New("No session to resume")
+	}
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	s.Import(*state)
+	if err := s.KeepAliveContext(ctx); err != nil {
+		s.Import(SessionState{})
+		return err
+	}
+	s.startKeepAlive()
+	return nil
+}
+
+// Saves the session state, if a store is configured.
+func (s *Session) saveState() error {
+	if s.config.TokenStore == nil {
+		return nil
+	}
+	state := s.Export()
+	if err := s.config.TokenStore.Save(&state); err != nil {
+		return errors.New("Cannot save session: " + err.Error())
+	}
+	return nil
+}